@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+)
+
+// headerFlags collects repeated -header "Key: Value" flags.
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("invalid header %q, expected \"Key: Value\"", value)
+	}
+	*h = append(*h, strings.TrimSpace(key)+": "+strings.TrimSpace(val))
+	return nil
+}
+
+// options returns the client options setting the headers.
+func (h *headerFlags) options() []ethclient.Option {
+	opts := make([]ethclient.Option, 0, len(*h))
+	for _, header := range *h {
+		key, val, _ := strings.Cut(header, ": ")
+		opts = append(opts, ethclient.WithHeader(key, val))
+	}
+	return opts
+}
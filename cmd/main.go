@@ -5,6 +5,7 @@ import (
 	"flag"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,11 +17,29 @@ import (
 
 func main() {
 	initialBlock := flag.Int("block", defaultInitialBlock, "block number to start scanning from")
+	endpoints := flag.String("endpoints", endPoint, "comma separated list of JSON-RPC endpoints")
+	wsEndpoint := flag.String("ws", "", "WebSocket endpoint pushing new heads, the scanner only polls if empty")
+	follow := flag.String("follow", ethclient.TagLatest, "head block the scanner follows: latest, safe or finalized")
+	traces := flag.String("traces", "", "trace API used to detect internal transfers: debug (debug_traceBlockByNumber) or parity (trace_block), disabled if empty")
+	rps := flag.Float64("rps", 0, "maximum requests per second sent to the endpoints, unlimited if 0")
+	cups := flag.Float64("cups", 0, "maximum compute units per second sent to the endpoints, weighting methods like hosted providers, takes precedence over -rps")
+	maxConcurrent := flag.Int("max-concurrent", 0, "maximum requests in flight, unlimited if 0")
+	mempool := flag.Bool("mempool", false, "record pending transactions of subscribed addresses until they are mined, dropped or replaced")
+	chainID := flag.Uint64("chain-id", 1, "chain id every endpoint must serve, mainnet by default, not checked if 0")
+	cacheSize := flag.Int("cache", 0, "size in MB of the cache of finalized blocks, receipts and state, disabled if 0")
+	var headers headerFlags
+	flag.Var(&headers, "header", "header sent with every RPC request, e.g. \"X-Api-Key: <key>\", can be repeated")
 	flag.Parse()
 
 	// Initialize the logger
 	logs.SetLevel(context.Background(), logs.LevelInfo)
 
+	head, err := ethclient.ParseBlockRef(*follow)
+	if err != nil || (head != ethclient.LatestBlock && head != ethclient.SafeBlock && head != ethclient.FinalizedBlock) {
+		logs.CtxFatal(context.Background(), "invalid -follow value: %s", *follow)
+		return
+	}
+
 	subscribeDal, err := dal.NewSubscribeDal()
 	if err != nil {
 		logs.CtxFatal(context.Background(), err.Error())
@@ -31,12 +50,42 @@ func main() {
 		logs.CtxFatal(context.Background(), err.Error())
 		return
 	}
-	parser, err := service.NewEthereumParser(subscribeDal, transactionDal)
+
+	cliOpts := append(headers.options(), ethclient.WithRetryPolicy(ethclient.DefaultRetryPolicy()))
+	if *rps > 0 || *cups > 0 || *maxConcurrent > 0 {
+		limit := ethclient.RateLimitConfig{Rate: *rps, MaxConcurrent: *maxConcurrent}
+		if *cups > 0 {
+			limit.Rate = *cups
+			limit.Costs = ethclient.DefaultComputeUnits()
+		}
+		cliOpts = append(cliOpts, ethclient.WithRateLimit(limit))
+	}
+	var cache *ethclient.Cache
+	if *cacheSize > 0 {
+		config := ethclient.DefaultCacheConfig()
+		config.MaxBytes = int64(*cacheSize) << 20
+		cache = ethclient.NewCache(config)
+		cliOpts = append(cliOpts, ethclient.WithInterceptors(cache.Interceptor()))
+	}
+	ethCli, err := ethclient.NewETHPoolClient(strings.Split(*endpoints, ","), ethclient.DefaultPoolConfig(), cliOpts...)
+	if err != nil {
+		logs.CtxFatal(context.Background(), err.Error())
+		return
+	}
+	defer ethCli.Close()
+	if *chainID != 0 {
+		if err := ethCli.VerifyChain(context.Background(), *chainID); err != nil {
+			logs.CtxFatal(context.Background(), "refusing to start: %s", err)
+			return
+		}
+	}
+
+	parser, err := service.NewEthereumParser(subscribeDal, transactionDal, ethCli)
 	if err != nil {
 		logs.CtxFatal(context.Background(), err.Error())
 		return
 	}
-	
+
 	srv, err := service.NewService(context.Background(), parser)
 	if err != nil {
 		logs.CtxFatal(context.Background(), err.Error())
@@ -45,10 +94,41 @@ func main() {
 	// Start the service, receive command line arguments
 	srv.Start(context.Background())
 
-	ethCli := ethclient.NewETHClient(endPoint)
-	scanService := service.NewScan(context.Background(), transactionDal, subscribeDal, ethCli, *initialBlock, time.Second*10)
-	// Start blockchain service, pull block transactions information every 10 seconds
+	scanOpts := []service.ScanOption{service.WithHeadBlock(head), service.WithChainID(*chainID)}
+	if cache != nil {
+		scanOpts = append(scanOpts, service.WithCache(cache))
+	}
+	switch *traces {
+	case "":
+	case "debug":
+		scanOpts = append(scanOpts, service.WithInternalTransfers(ethclient.TraceDebug))
+	case "parity":
+		scanOpts = append(scanOpts, service.WithInternalTransfers(ethclient.TraceParity))
+	default:
+		logs.CtxFatal(context.Background(), "invalid -traces value: %s", *traces)
+		return
+	}
+	var mempoolOpts []service.MempoolOption
+	if *wsEndpoint != "" {
+		headCli := ethclient.NewETHClient(*wsEndpoint, headers.options()...)
+		defer headCli.Close()
+		if *chainID != 0 {
+			if err := headCli.VerifyChain(context.Background(), *chainID); err != nil {
+				logs.CtxFatal(context.Background(), "refusing to start: %s", err)
+				return
+			}
+		}
+		scanOpts = append(scanOpts, service.WithHeadSubscription(headCli))
+		mempoolOpts = append(mempoolOpts, service.WithPendingSubscription(headCli))
+	}
+	scanService := service.NewScan(context.Background(), transactionDal, subscribeDal, ethCli, *initialBlock, time.Second*10, scanOpts...)
+	// Start blockchain service, scan on every pushed head or pull block transactions information every 10 seconds
 	scanService.Run()
+	if *mempool {
+		mempoolWatcher := service.NewMempoolWatcher(context.Background(), transactionDal, subscribeDal, ethCli, time.Second*2, mempoolOpts...)
+		mempoolWatcher.Run()
+		defer mempoolWatcher.Stop()
+	}
 
 	// Mock data
 	mockData(transactionDal, subscribeDal)
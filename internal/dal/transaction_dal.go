@@ -2,6 +2,8 @@ package dal
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -35,6 +37,9 @@ func (t *TransactionDal) TransactionByAddr(ctx context.Context, addr string) []*
 	return transactions
 }
 
+// SaveTransaction appends the transactions of the address. A transaction
+// replaces the pending record with the same hash not yet read, so that it
+// is read once with its latest status.
 func (t *TransactionDal) SaveTransaction(ctx context.Context, addr string, transactions []*types.Transaction) error {
 	logs.CtxDebug(ctx, "SaveTransaction addr [%s] transactions number [%d]", addr, len(transactions))
 	t.lock.Lock()
@@ -42,7 +47,13 @@ func (t *TransactionDal) SaveTransaction(ctx context.Context, addr string, trans
 
 	olds, ok := t.data[addr]
 	if ok {
-		olds = append(olds, transactions...)
+		for _, transaction := range transactions {
+			if i := pendingIndex(olds, transaction); i >= 0 {
+				olds[i] = transaction
+			} else {
+				olds = append(olds, transaction)
+			}
+		}
 		t.data[addr] = olds
 		return nil
 	}
@@ -51,6 +62,44 @@ func (t *TransactionDal) SaveTransaction(ctx context.Context, addr string, trans
 	return nil
 }
 
+// pendingIndex returns the index in olds of the pending record of the
+// transaction, -1 if there is none.
+func pendingIndex(olds []*types.Transaction, transaction *types.Transaction) int {
+	if transaction.Kind != "" {
+		return -1
+	}
+	for i, old := range olds {
+		if old.Hash == transaction.Hash && old.Kind == "" && old.Status == types.StatusPending {
+			return i
+		}
+	}
+	return -1
+}
+
+// DeleteBlock drops the records of the given block not read yet, after the
+// block was reorganized out of the chain.
+func (t *TransactionDal) DeleteBlock(ctx context.Context, blockNum int) {
+	logs.CtxDebug(ctx, "DeleteBlock block [%d]", blockNum)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for addr, olds := range t.data {
+		kept := olds[:0]
+		for _, transaction := range olds {
+			number, err := strconv.ParseInt(strings.TrimPrefix(transaction.BlockNumber, "0x"), 16, 64)
+			if err == nil && int(number) == blockNum {
+				continue
+			}
+			kept = append(kept, transaction)
+		}
+		if len(kept) == 0 {
+			delete(t.data, addr)
+		} else {
+			t.data[addr] = kept
+		}
+	}
+}
+
 func (t *TransactionDal) GetCurrentBlock(ctx context.Context) int {
 	return int(atomic.LoadInt64(&t.currentBlock))
 }
@@ -67,8 +67,6 @@ func (s *Service) listenForCommands() error {
 			s.handleCommand(command)
 		}
 	}
-
-	return nil
 }
 
 func (s *Service) handleCommand(input string) {
@@ -112,6 +110,57 @@ func (s *Service) handleCommand(input string) {
 		} else {
 			logs.CtxInfo(currentCtx, "No transactions found for address: %s", args[1])
 		}
+	case "getBalance":
+		if len(args) < 2 || len(args) > 3 {
+			logs.CtxInfo(currentCtx, "Invalid number of arguments. Usage: getBalance <address> [block]")
+			return
+		}
+		balance, err := s.parser.GetBalance(currentCtx, args[1], optionalArg(args, 2))
+		if err != nil {
+			logs.CtxInfo(currentCtx, "Failed to get balance of address: %s, err: %s", args[1], err)
+			return
+		}
+		logs.CtxInfo(currentCtx, "Balance: %s ETH (%s gwei, %s wei)", utils.FormatEther(balance), utils.FormatGwei(balance), balance)
+	case "getNonce":
+		if len(args) < 2 || len(args) > 3 {
+			logs.CtxInfo(currentCtx, "Invalid number of arguments. Usage: getNonce <address> [block]")
+			return
+		}
+		nonce, err := s.parser.GetNonce(currentCtx, args[1], optionalArg(args, 2))
+		if err != nil {
+			logs.CtxInfo(currentCtx, "Failed to get nonce of address: %s, err: %s", args[1], err)
+			return
+		}
+		logs.CtxInfo(currentCtx, "Nonce: %d", nonce)
+	case "getTokenBalance":
+		if len(args) < 3 || len(args) > 4 {
+			logs.CtxInfo(currentCtx, "Invalid number of arguments. Usage: getTokenBalance <token> <address> [block]")
+			return
+		}
+		balance, err := s.parser.GetTokenBalance(currentCtx, args[1], args[2], optionalArg(args, 3))
+		if err != nil {
+			logs.CtxInfo(currentCtx, "Failed to get balance of address: %s in token: %s, err: %s", args[2], args[1], err)
+			return
+		}
+		logs.CtxInfo(currentCtx, "Balance: %s %s (%s)", utils.FormatUnits(balance.Balance, balance.Decimals), balance.Symbol, balance.Balance)
+	case "gasPrice":
+		if len(args) != 1 {
+			logs.CtxInfo(currentCtx, "Invalid number of arguments. Usage: gasPrice")
+			return
+		}
+		prices, err := s.parser.GetGasPrice(currentCtx)
+		if err != nil {
+			logs.CtxInfo(currentCtx, "Failed to get gas price, err: %s", err)
+			return
+		}
+		logs.CtxInfo(currentCtx, "Base fee: %s gwei, legacy gas price: %s gwei", utils.FormatGwei(prices.BaseFee), utils.FormatGwei(prices.GasPrice))
+		for _, tier := range []struct {
+			name       string
+			suggestion FeeSuggestion
+		}{{"Slow", prices.Slow}, {"Standard", prices.Standard}, {"Fast", prices.Fast}} {
+			logs.CtxInfo(currentCtx, "%s: max fee %s gwei, priority fee %s gwei", tier.name,
+				utils.FormatGwei(tier.suggestion.MaxFeePerGas), utils.FormatGwei(tier.suggestion.MaxPriorityFeePerGas))
+		}
 	case "help":
 		printHelp()
 	default:
@@ -120,11 +169,23 @@ func (s *Service) handleCommand(input string) {
 	}
 }
 
+// optionalArg returns the argument at index i, empty if not provided
+func optionalArg(args []string, i int) string {
+	if len(args) > i {
+		return args[i]
+	}
+	return ""
+}
+
 // 打印帮助信息
 func printHelp() {
 	fmt.Println("Usage:")
-	fmt.Println("  getCurrentBlock            - Subscribed the latest block number")
-	fmt.Println("  subscribe <address>        - Subscribe to monitor a specific address")
-	fmt.Println("  getTransactions <address>  - Subscribed transactions related to a specific address")
-	fmt.Println("  help                       - Show available commands and usage")
+	fmt.Println("  getCurrentBlock                           - Subscribed the latest block number")
+	fmt.Println("  subscribe <address>                       - Subscribe to monitor a specific address")
+	fmt.Println("  getTransactions <address>                 - Subscribed transactions related to a specific address")
+	fmt.Println("  getBalance <address> [block]              - Balance of an address at a block, latest by default")
+	fmt.Println("  getNonce <address> [block]                - Number of transactions sent by an address at a block, latest by default")
+	fmt.Println("  getTokenBalance <token> <address> [block] - ERC-20 balance of an address at a block, latest by default")
+	fmt.Println("  gasPrice                                  - Slow, standard and fast EIP-1559 fee suggestions for the next block")
+	fmt.Println("  help                                      - Show available commands and usage")
 }
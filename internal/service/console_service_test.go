@@ -0,0 +1,122 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/352174109/trustwallet-homework/pkg/abi"
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+	"github.com/352174109/trustwallet-homework/pkg/ethtest"
+)
+
+const usdc = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+
+// serveToken answers the ERC-20 calls of the console with a 6 decimals
+// token holding 1.5 units for every address.
+func serveToken(params []json.RawMessage) (interface{}, error) {
+	var msg struct {
+		Input string `json:"input"`
+	}
+	if err := json.Unmarshal(params[0], &msg); err != nil {
+		return nil, err
+	}
+	input, err := hex.DecodeString(strings.TrimPrefix(msg.Input, "0x"))
+	if err != nil || len(input) < 4 {
+		return nil, &ethclient.RPCError{Code: ethclient.CodeExecutionReverted, Message: "execution reverted"}
+	}
+
+	var result []byte
+	switch selector := input[:4]; {
+	case bytes.Equal(selector, abi.ERC20BalanceOf.Selector()):
+		result, err = abi.Pack(abi.ERC20BalanceOf.Outputs, big.NewInt(1500000))
+	case bytes.Equal(selector, abi.ERC20Decimals.Selector()):
+		result, err = abi.Pack(abi.ERC20Decimals.Outputs, big.NewInt(6))
+	case bytes.Equal(selector, abi.ERC20Symbol.Selector()):
+		result, err = abi.Pack(abi.ERC20Symbol.Outputs, "USDC")
+	default:
+		return nil, &ethclient.RPCError{Code: ethclient.CodeExecutionReverted, Message: "execution reverted"}
+	}
+	return "0x" + hex.EncodeToString(result), err
+}
+
+func TestConsole(t *testing.T) {
+	f := newScanFixture(t, 1)
+	f.node.SetBalance(alice, big.NewInt(1500000000000000000))
+	f.node.HandleMethod(ethclient.EthCall, serveToken)
+	serveFees(f.node)
+	f.node.MineEmpty(1)
+	block := f.node.Mine(
+		ethtest.Tx{From: alice, To: bob, Value: big.NewInt(1)},
+		ethtest.Tx{From: alice, To: carol, Value: big.NewInt(2)},
+	)
+
+	srv, _ := NewService(context.Background(), f.parser)
+	// The scanner logs from its own goroutine.
+	var output syncBuffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+	run := func(command string) string {
+		output.Reset()
+		srv.handleCommand(command)
+		return output.String()
+	}
+
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"subscribe " + bob, "Subscribed to address: " + bob},
+		{"getBalance " + alice, "Balance: 1.5 ETH (1500000000 gwei, 1500000000000000000 wei)"},
+		{"getNonce " + alice, "Nonce: 2"},
+		{"getNonce " + alice + " 0x0", "Nonce: 0"},
+		{"getNonce " + alice + " 0x5", "Failed to get nonce of address"},
+		{"getTokenBalance " + usdc + " " + alice, "Balance: 1.5 USDC (1500000)"},
+		{"getTransactions " + bob, "No transactions found for address: " + bob},
+		{"gasPrice", "Standard: max fee 22 gwei, priority fee 2 gwei"},
+	}
+	for _, test := range tests {
+		if out := run(test.command); !strings.Contains(out, test.expected) {
+			t.Errorf("%s: expected %q in output %q", test.command, test.expected, out)
+		}
+	}
+
+	f.scanner.Run()
+	f.waitForBlock(t, block.Number)
+	if out := run("getCurrentBlock"); !strings.Contains(out, "Current Block: 2") {
+		t.Errorf("unexpected current block output %q", out)
+	}
+	if out := run("getTransactions " + bob); !strings.Contains(out, block.Transactions[0].Hash) {
+		t.Errorf("expected transaction %s in output %q", block.Transactions[0].Hash, out)
+	}
+}
+
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Reset() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.buf.Reset()
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
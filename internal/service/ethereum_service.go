@@ -2,6 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +16,29 @@ import (
 	"github.com/352174109/trustwallet-homework/pkg/utils"
 )
 
+const (
+	// maxBatchBlocks is the maximum number of blocks fetched in one batch
+	// request while catching up with the head block.
+	maxBatchBlocks = 50
+
+	// headStaleIntervals is the number of scan intervals without a pushed
+	// head after which the scanner polls again.
+	headStaleIntervals = 3
+
+	// blockReceiptsThreshold is the number of matched transactions in a
+	// block from which all receipts of the block are fetched at once
+	// instead of one by one.
+	blockReceiptsThreshold = 10
+
+	// maxReorgDepth is the number of scanned block hashes kept to detect
+	// the reorgs of the blocks behind the head.
+	maxReorgDepth = 64
+)
+
+// errReorg is returned for a block whose parent is not the scanned one,
+// the scanner going back to scan the parent again.
+var errReorg = errors.New("parent block reorganized")
+
 type Scanner interface {
 	Run()
 
@@ -26,6 +53,7 @@ type BlockScan struct {
 	cancel context.CancelFunc
 
 	cli            *ethclient.Client
+	headCli        *ethclient.Client
 	transactionDal *dal.TransactionDal
 	subscribeDal   *dal.SubscribeDal
 
@@ -34,14 +62,86 @@ type BlockScan struct {
 	interval         time.Duration
 	lastScannedBlock int
 
+	// head is the block the scanner follows, the latest one by default.
+	head ethclient.BlockRef
+
+	// noBlockReceipts is set once the node rejected eth_getBlockReceipts.
+	noBlockReceipts bool
+
+	// traceInternal enables the detection of internal transfers with the
+	// traceAPI, until the node rejects it and noTraces is set.
+	traceInternal bool
+	traceAPI      ethclient.TraceAPI
+	noTraces      bool
+
+	// chainID is the chain every scanned transaction must belong to, not
+	// checked if 0.
+	chainID uint64
+
+	// hashes are the hashes of the last scanned blocks by number.
+	hashes map[int]string
+	// cache, if set, drops the results of the reorganized blocks.
+	cache *ethclient.Cache
+
 	once sync.Once
 }
 
-func NewScan(ctx context.Context, transactionDal *dal.TransactionDal, subscribeDal *dal.SubscribeDal, cli *ethclient.Client, startAt int, interval time.Duration) Scanner {
+// ScanOption configures a BlockScan.
+type ScanOption func(*BlockScan)
+
+// WithHeadSubscription drives the scanner with the newHeads notifications
+// of cli, which must use a push transport. The scanner polls every
+// interval while no heads are pushed, e.g. when the socket is down.
+func WithHeadSubscription(cli *ethclient.Client) ScanOption {
+	return func(b *BlockScan) {
+		b.headCli = cli
+	}
+}
+
+// WithHeadBlock makes the scanner follow the given block instead of the
+// latest one, e.g. ethclient.FinalizedBlock to only scan blocks which can
+// no longer be reorganized, at the cost of a delay of a few minutes.
+func WithHeadBlock(head ethclient.BlockRef) ScanOption {
+	return func(b *BlockScan) {
+		b.head = head
+	}
+}
+
+// WithInternalTransfers makes the scanner trace every block with the given
+// API to also save the ether moved to or from subscribed addresses by
+// contracts, e.g. withdrawals from a multisig or an exchange. Tracing is
+// expensive and usually not offered by public endpoints.
+func WithInternalTransfers(api ethclient.TraceAPI) ScanOption {
+	return func(b *BlockScan) {
+		b.traceInternal = true
+		b.traceAPI = api
+	}
+}
+
+// WithChainID makes the scanner refuse blocks holding transactions of
+// another chain than chainID, e.g. served by an endpoint which switched
+// networks. Such blocks are not saved and the endpoints are verified
+// again, ejecting the faulty ones from a pool.
+func WithChainID(chainID uint64) ScanOption {
+	return func(b *BlockScan) {
+		b.chainID = chainID
+	}
+}
+
+// WithCache makes the scanner invalidate the results of the blocks it finds
+// reorganized in cache, the cache of the client. It could otherwise keep
+// serving them, e.g. after a block considered final was reorganized.
+func WithCache(cache *ethclient.Cache) ScanOption {
+	return func(b *BlockScan) {
+		b.cache = cache
+	}
+}
+
+func NewScan(ctx context.Context, transactionDal *dal.TransactionDal, subscribeDal *dal.SubscribeDal, cli *ethclient.Client, startAt int, interval time.Duration, opts ...ScanOption) Scanner {
 	logs.CtxInfo(ctx, "Blockchain set to start at block: %d", startAt)
 	ctx, cancel := context.WithCancel(ctx)
 	transactionDal.SetCurrentBlock(ctx, startAt)
-	return &BlockScan{
+	b := &BlockScan{
 		ctx:    ctx,
 		cancel: cancel,
 
@@ -51,13 +151,18 @@ func NewScan(ctx context.Context, transactionDal *dal.TransactionDal, subscribeD
 
 		interval:         interval,
 		lastScannedBlock: startAt,
+		hashes:           make(map[int]string),
 	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
 }
 
 func (b *BlockScan) startScan(ctx context.Context) (int, error) {
-	headBlock, err := b.cli.BlockNumber(ctx)
+	headBlock, err := b.cli.BlockNumberAt(ctx, b.head)
 	if err != nil {
-		logs.CtxError(ctx, "error querying head block number: %s", err)
+		b.logScanError(ctx, "error querying head block number", err)
 		return 0, err
 	}
 
@@ -66,25 +171,117 @@ func (b *BlockScan) startScan(ctx context.Context) (int, error) {
 		return 0, nil
 	}
 
+	// More than one block behind head, catch up with a batch request.
+	if headBlock > nextBlockNum {
+		toBlockNum := nextBlockNum + maxBatchBlocks - 1
+		if toBlockNum > headBlock {
+			toBlockNum = headBlock
+		}
+		return b.scanBlocks(ctx, nextBlockNum, toBlockNum)
+	}
+
 	block, err := b.scanBlock(ctx, nextBlockNum)
+	if errors.Is(err, ethclient.ErrBlockNotFound) {
+		// The node reported a head it has not served yet, retry on the next tick.
+		logs.CtxDebug(ctx, "block %d not available yet", nextBlockNum)
+		return 0, nil
+	}
 	if err != nil {
-		logs.CtxError(ctx, "error scanning block: ", err)
+		b.logScanError(ctx, "error scanning block", err)
 		return 0, err
 	}
 
-	err = b.saveBlock(ctx, block.Transactions)
+	if err := b.processBlock(ctx, nextBlockNum, block); errors.Is(err, errReorg) {
+		return b.lastScannedBlock, nil
+	} else if err != nil {
+		b.logScanError(ctx, "error saving block", err)
+		return 0, err
+	}
+
+	return b.lastScannedBlock, nil
+}
+
+// scanBlocks retrieves the blocks in the range [from, to] in a single
+// batch and processes them in order. Blocks following a failed one are
+// left to the next scan.
+func (b *BlockScan) scanBlocks(ctx context.Context, from, to int) (int, error) {
+	logs.CtxDebug(ctx, "scanning blocks [%d, %d]", from, to)
+	blocks, err := b.cli.BlocksByNumberFiltered(ctx, from, to, b.transactionFilter(ctx))
+	for i, block := range blocks {
+		if err := b.processBlock(ctx, from+i, block); errors.Is(err, errReorg) {
+			return b.lastScannedBlock, nil
+		} else if err != nil {
+			b.logScanError(ctx, "error saving block", err)
+			return 0, err
+		}
+	}
+	if errors.Is(err, ethclient.ErrBlockNotFound) && len(blocks) > 0 {
+		// Continue from the missing block, it may be served by now.
+		logs.CtxDebug(ctx, "block %d not available yet", from+len(blocks))
+		return b.lastScannedBlock, nil
+	}
+	if errors.Is(err, ethclient.ErrBlockNotFound) {
+		logs.CtxDebug(ctx, "block %d not available yet", from)
+		return 0, nil
+	}
 	if err != nil {
-		logs.CtxError(ctx, "error saving block: ", err)
+		b.logScanError(ctx, "error scanning blocks", err)
+		return 0, err
 	}
-	b.lastScannedBlock = nextBlockNum
-	b.transactionDal.SetCurrentBlock(ctx, nextBlockNum)
 
 	return b.lastScannedBlock, nil
 }
 
-// GetCurrentBlock returns the last scanned block.
+// processBlock saves the transactions of the block and marks it as scanned.
+// A block failing to be saved is scanned again on the next run. A block
+// whose parent is not the scanned one is not saved, the parent is
+// scanned again and errReorg is returned.
+func (b *BlockScan) processBlock(ctx context.Context, blockNum int, block *ethclient.ETHBlock) error {
+	if hash, ok := b.hashes[blockNum-1]; ok && hash != block.ParentHash {
+		b.rewind(ctx, blockNum-1)
+		return errReorg
+	}
+	if err := b.saveBlock(ctx, blockNum, block); err != nil {
+		return err
+	}
+	b.hashes[blockNum] = block.Hash
+	delete(b.hashes, blockNum-maxReorgDepth)
+	b.lastScannedBlock = blockNum
+	b.transactionDal.SetCurrentBlock(ctx, blockNum)
+	return nil
+}
+
+// rewind marks the blocks from blockNum as not scanned after they were
+// reorganized, and drops their records not read yet and their cached
+// results. Records already read are not taken back.
+func (b *BlockScan) rewind(ctx context.Context, blockNum int) {
+	logs.CtxWarn(ctx, "block %d reorganized, scanning it again", blockNum)
+	b.transactionDal.DeleteBlock(ctx, blockNum)
+	if b.cache != nil {
+		b.cache.Invalidate(blockNum)
+	}
+	delete(b.hashes, blockNum)
+	b.lastScannedBlock = blockNum - 1
+	b.transactionDal.SetCurrentBlock(ctx, blockNum-1)
+}
+
+// logScanError logs a failed RPC request at a level matching its cause.
+func (b *BlockScan) logScanError(ctx context.Context, msg string, err error) {
+	var rpcErr *ethclient.RPCError
+	switch {
+	case errors.Is(err, ethclient.ErrRateLimited):
+		logs.CtxWarn(ctx, "%s: provider rate limit reached, waiting for next tick: %s", msg, err)
+	case errors.As(err, &rpcErr):
+		logs.CtxError(ctx, "%s: rpc error code [%d] message [%s]", msg, rpcErr.Code, rpcErr.Message)
+	default:
+		logs.CtxError(ctx, "%s: %s", msg, err)
+	}
+}
+
+// GetCurrentBlock returns the last scanned block. It is read from the
+// dal, lastScannedBlock is owned by the scanning goroutine.
 func (b *BlockScan) GetCurrentBlock() int {
-	return b.lastScannedBlock
+	return b.transactionDal.GetCurrentBlock(b.ctx)
 }
 
 // Run starts the block scanning process. It will return the number
@@ -97,25 +294,81 @@ func (b *BlockScan) Run() {
 }
 
 func (b *BlockScan) run() error {
+	heads := make(chan *ethclient.Header, 16)
+	sub := b.subscribeHeads(heads)
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+
+	var subErrs <-chan error
+	if sub != nil {
+		subErrs = sub.Err()
+	}
+	var lastHeadAt time.Time
+
 	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-b.ctx.Done():
-			ticker.Stop()
 			logs.CtxInfo(b.ctx, "stopping blockchain")
 			return nil
+		case header := <-heads:
+			lastHeadAt = time.Now()
+			logs.CtxDebug(b.ctx, "new head %s pushed", header.Number)
+			b.catchUp()
+		case err, ok := <-subErrs:
+			if !ok {
+				subErrs, sub = nil, nil
+				continue
+			}
+			logs.CtxWarn(b.ctx, "head subscription interrupted, polling until it is back: %s", err)
+			lastHeadAt = time.Time{}
 		case <-ticker.C:
-			ctx := context.Background()
-			for scannedBlock, err := b.startScan(ctx); scannedBlock != 0 || err != nil; scannedBlock, err = b.startScan(ctx) {
-				if err != nil {
-					break
+			if b.headCli != nil && sub == nil {
+				if sub = b.subscribeHeads(heads); sub != nil {
+					subErrs = sub.Err()
 				}
 			}
-			ticker.Reset(b.interval)
-			logs.CtxDebug(ctx, "last scanned block %d\n", b.GetCurrentBlock())
+			// Heads are pushed, no need to poll.
+			if time.Since(lastHeadAt) < b.interval*headStaleIntervals {
+				continue
+			}
+			b.catchUp()
 		}
 	}
-	return nil
+}
+
+// subscribeHeads subscribes to new heads if a push client is configured.
+// It returns nil if there is none or the subscription failed.
+func (b *BlockScan) subscribeHeads(heads chan<- *ethclient.Header) *ethclient.ClientSubscription {
+	if b.headCli == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(b.ctx, b.interval)
+	defer cancel()
+	sub, err := b.headCli.SubscribeNewHeads(ctx, heads)
+	if err != nil {
+		logs.CtxWarn(b.ctx, "error subscribing to new heads, polling instead: %s", err)
+		return nil
+	}
+	logs.CtxInfo(b.ctx, "subscribed to new heads")
+	return sub
+}
+
+// catchUp scans blocks until the head block is reached or a scan fails.
+func (b *BlockScan) catchUp() {
+	// Use the scanner context so Stop also interrupts retries.
+	ctx := b.ctx
+	for scannedBlock, err := b.startScan(ctx); scannedBlock != 0 || err != nil; scannedBlock, err = b.startScan(ctx) {
+		if err != nil {
+			break
+		}
+	}
+	logs.CtxDebug(ctx, "last scanned block %d\n", b.GetCurrentBlock())
 }
 
 func (b *BlockScan) Stop() error {
@@ -127,17 +380,56 @@ func (b *BlockScan) Stop() error {
 // returns a map containing the ingoing/outgoing transactions for
 // the addresses subscribed.
 func (b *BlockScan) scanBlock(ctx context.Context, blockNumber int) (*ethclient.ETHBlock, error) {
-	block, err := b.cli.BlockByNumber(ctx, blockNumber)
+	block, err := b.cli.BlockByNumberFiltered(ctx, blockNumber, b.transactionFilter(ctx))
 	if err != nil {
-		logs.CtxError(ctx, "error querying block: %s", err.Error())
 		return nil, err
 	}
 
 	return block, nil
 }
 
-func (b *BlockScan) saveBlock(ctx context.Context, transactions []*ethclient.ETHTransaction) error {
-	transactionMapByAddr := b.convertToInternalBlock(ctx, transactions)
+// transactionFilter returns the filter of the transactions decoded from
+// the scanned blocks, the ones touching subscribed addresses and the ones
+// of another chain, for checkChain to refuse the block. All transactions
+// are kept while internal transfers are traced, as traces refer to every
+// transaction of the block.
+func (b *BlockScan) transactionFilter(ctx context.Context) ethclient.TransactionFilter {
+	if b.traceInternal && !b.noTraces {
+		return nil
+	}
+	return func(tx ethclient.TransactionSummary) bool {
+		return b.foreignChain(tx.ChainID) ||
+			b.subscribeDal.Subscribed(ctx, tx.From) || b.subscribeDal.Subscribed(ctx, tx.To)
+	}
+}
+
+// foreignChain reports whether the hex chain id of a transaction is not the
+// configured one. Unparsable chain ids are reported for checkChain to
+// fail on them.
+func (b *BlockScan) foreignChain(chainID string) bool {
+	if b.chainID == 0 || chainID == "" {
+		return false
+	}
+	parsed, err := strconv.ParseUint(strings.TrimPrefix(chainID, "0x"), 16, 64)
+	return err != nil || parsed != b.chainID
+}
+
+func (b *BlockScan) saveBlock(ctx context.Context, blockNum int, block *ethclient.ETHBlock) error {
+	if err := b.checkChain(ctx, block); err != nil {
+		return err
+	}
+
+	transactionMapByAddr := b.convertToInternalBlock(ctx, block.Transactions)
+	if len(transactionMapByAddr) > 0 {
+		if err := b.enrichWithReceipts(ctx, blockNum, transactionMapByAddr); err != nil {
+			return fmt.Errorf("error querying receipts: %w", err)
+		}
+	}
+
+	if err := b.addInternalTransfers(ctx, blockNum, block, transactionMapByAddr); err != nil {
+		return fmt.Errorf("error tracing internal transfers: %w", err)
+	}
+	b.addWithdrawals(ctx, block, transactionMapByAddr)
 
 	for addr, txs := range transactionMapByAddr {
 		b.transactionDal.SaveTransaction(ctx, addr, txs)
@@ -145,6 +437,175 @@ func (b *BlockScan) saveBlock(ctx context.Context, transactions []*ethclient.ETH
 	return nil
 }
 
+// checkChain returns ethclient.ErrChainMismatch if a transaction of the
+// block belongs to another chain than the configured one. Legacy
+// transactions without replay protection carry no chain id and pass. The
+// scanned blocks keep every transaction of another chain, see
+// transactionFilter.
+func (b *BlockScan) checkChain(ctx context.Context, block *ethclient.ETHBlock) error {
+	if b.chainID == 0 {
+		return nil
+	}
+
+	for _, tx := range block.Transactions {
+		if tx.ChainId == "" {
+			continue
+		}
+		chainID, err := strconv.ParseUint(strings.TrimPrefix(tx.ChainId, "0x"), 16, 64)
+		if err != nil {
+			return fmt.Errorf("error parsing chain id of transaction %s: %w", tx.Hash, err)
+		}
+		if chainID == b.chainID {
+			continue
+		}
+
+		// Eject the endpoint which served the block, if there is another one.
+		if err := b.cli.VerifyChain(ctx, b.chainID); err != nil {
+			logs.CtxError(ctx, "error verifying the chain of the endpoints: %s", err)
+		}
+		return fmt.Errorf("%w: transaction %s of block %s belongs to chain %d, expected %d",
+			ethclient.ErrChainMismatch, tx.Hash, block.Number, chainID, b.chainID)
+	}
+	return nil
+}
+
+// enrichWithReceipts sets the execution outcome of the matched
+// transactions of the block from their receipts.
+func (b *BlockScan) enrichWithReceipts(ctx context.Context, blockNum int, transactionMapByAddr map[string][]*types.Transaction) error {
+	matched := make(map[string]*types.Transaction)
+	for _, txs := range transactionMapByAddr {
+		for _, tx := range txs {
+			matched[tx.Hash] = tx
+		}
+	}
+
+	receipts, err := b.fetchReceipts(ctx, blockNum, matched)
+	if err != nil {
+		return err
+	}
+
+	for _, receipt := range receipts {
+		tx, ok := matched[receipt.TransactionHash]
+		if !ok {
+			continue
+		}
+		applyReceipt(tx, receipt)
+		delete(matched, receipt.TransactionHash)
+	}
+	for hash := range matched {
+		return fmt.Errorf("missing receipt for transaction %s", hash)
+	}
+	return nil
+}
+
+// fetchReceipts returns the receipts of the matched transactions, possibly
+// along with the other receipts of the block.
+func (b *BlockScan) fetchReceipts(ctx context.Context, blockNum int, matched map[string]*types.Transaction) ([]*ethclient.Receipt, error) {
+	if len(matched) >= blockReceiptsThreshold && !b.noBlockReceipts {
+		receipts, err := b.cli.BlockReceipts(ctx, ethclient.BlockNumberRef(blockNum))
+		var rpcErr *ethclient.RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == ethclient.CodeMethodNotFound {
+			logs.CtxWarn(ctx, "eth_getBlockReceipts not supported, fetching receipts by transaction")
+			b.noBlockReceipts = true
+		} else {
+			return receipts, err
+		}
+	}
+
+	hashes := make([]string, 0, len(matched))
+	for hash := range matched {
+		hashes = append(hashes, hash)
+	}
+	return b.cli.TransactionReceipts(ctx, hashes)
+}
+
+func applyReceipt(tx *types.Transaction, receipt *ethclient.Receipt) {
+	tx.Status = receipt.Status
+	tx.GasUsed = receipt.GasUsed
+	tx.CumulativeGasUsed = receipt.CumulativeGasUsed
+	tx.EffectiveGasPrice = receipt.EffectiveGasPrice
+	tx.ContractAddress = receipt.ContractAddress
+	tx.Logs = make([]*types.Log, 0, len(receipt.Logs))
+	for _, log := range receipt.Logs {
+		tx.Logs = append(tx.Logs, &types.Log{
+			Address:  log.Address,
+			Topics:   log.Topics,
+			Data:     log.Data,
+			LogIndex: log.LogIndex,
+		})
+	}
+}
+
+// addInternalTransfers adds to transactionMapByAddr the ether transfers
+// made by contracts to or from subscribed addresses in the block.
+func (b *BlockScan) addInternalTransfers(ctx context.Context, blockNum int, block *ethclient.ETHBlock, transactionMapByAddr map[string][]*types.Transaction) error {
+	if !b.traceInternal || b.noTraces || len(block.Transactions) == 0 {
+		return nil
+	}
+
+	hashes := make([]string, len(block.Transactions))
+	chainIDs := make(map[string]string, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		hashes[i] = tx.Hash
+		chainIDs[tx.Hash] = tx.ChainId
+	}
+	calls, err := b.cli.InternalCalls(ctx, blockNum, b.traceAPI, hashes)
+	var rpcErr *ethclient.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == ethclient.CodeMethodNotFound {
+		logs.CtxWarn(ctx, "tracing not supported by the node, internal transfers are not detected: %s", err)
+		b.noTraces = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, call := range calls {
+		if !call.TransfersValue() {
+			continue
+		}
+		transfer := &types.Transaction{
+			ChainID:     chainIDs[call.TxHash],
+			BlockNumber: block.Number,
+			Hash:        call.TxHash,
+			From:        call.From,
+			To:          call.To,
+			Value:       call.Value,
+			Gas:         call.Gas,
+			Input:       call.Input,
+			Status:      types.StatusSuccess,
+			Kind:        types.KindInternal,
+			CallType:    call.Type,
+			CallPath:    call.Path,
+		}
+		for _, addr := range []string{transfer.From, transfer.To} {
+			if b.subscribeDal.Subscribed(ctx, addr) {
+				transactionMapByAddr[addr] = append(transactionMapByAddr[addr], transfer)
+			}
+		}
+	}
+	return nil
+}
+
+// addWithdrawals adds to transactionMapByAddr the beacon chain withdrawals
+// of the block credited to subscribed addresses.
+func (b *BlockScan) addWithdrawals(ctx context.Context, block *ethclient.ETHBlock, transactionMapByAddr map[string][]*types.Transaction) {
+	for _, withdrawal := range block.Withdrawals {
+		if !b.subscribeDal.Subscribed(ctx, withdrawal.Address) {
+			continue
+		}
+		transactionMapByAddr[withdrawal.Address] = append(transactionMapByAddr[withdrawal.Address], &types.Transaction{
+			BlockNumber:     block.Number,
+			To:              withdrawal.Address,
+			Value:           "0x" + withdrawal.AmountWei().Text(16),
+			Status:          types.StatusSuccess,
+			Kind:            types.KindWithdrawal,
+			WithdrawalIndex: fmt.Sprintf("0x%x", withdrawal.Index),
+			ValidatorIndex:  fmt.Sprintf("0x%x", withdrawal.ValidatorIndex),
+		})
+	}
+}
+
 // convertToInternalBlock converts a list of ethclient.ETHTransaction into a list of
 // types.Transaction.
 func (b *BlockScan) convertToInternalBlock(ctx context.Context, txs []*ethclient.ETHTransaction) map[string][]*types.Transaction {
@@ -186,10 +647,11 @@ func (b *BlockScan) convertToInternalBlock(ctx context.Context, txs []*ethclient
 }
 
 // nextBlock returns the next block to be scanned. It will return
-// 0 if there is any pending block to be scanned. If the last scanned
-// block is 0 it will return the head block number.
+// 0 if there is no pending block to be scanned, the last scanned block
+// being above a safe or finalized head until it catches up. If the last
+// scanned block is 0 it will return the head block number.
 func nextBlock(lastScannedBlock, headBlock int) int {
-	if lastScannedBlock == headBlock {
+	if lastScannedBlock >= headBlock {
 		return 0
 	}
 	if lastScannedBlock == 0 {
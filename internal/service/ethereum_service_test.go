@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/352174109/trustwallet-homework/internal/dal"
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+	"github.com/352174109/trustwallet-homework/pkg/ethtest"
+	"github.com/352174109/trustwallet-homework/pkg/types"
+)
+
+const (
+	alice = "0x00000000000000000000000000000000000a11ce"
+	bob   = "0x0000000000000000000000000000000000000b0b"
+	carol = "0x00000000000000000000000000000000000ca201"
+)
+
+// scanFixture is a scanner and a parser running against a fake node.
+type scanFixture struct {
+	node    *ethtest.Node
+	parser  Parser
+	scanner Scanner
+
+	subscribeDal   *dal.SubscribeDal
+	transactionDal *dal.TransactionDal
+}
+
+func newScanFixture(t *testing.T, startAt int, opts ...ScanOption) *scanFixture {
+	return newClientScanFixture(t, startAt, nil, opts...)
+}
+
+// newClientScanFixture is newScanFixture with a client of the node created
+// with cliOpts.
+func newClientScanFixture(t *testing.T, startAt int, cliOpts []ethclient.Option, opts ...ScanOption) *scanFixture {
+	node := ethtest.NewNode()
+	t.Cleanup(node.Close)
+
+	subscribeDal, _ := dal.NewSubscribeDal()
+	transactionDal, _ := dal.NewTransactionDal()
+	cli := node.Client(cliOpts...)
+	parser, _ := NewEthereumParser(subscribeDal, transactionDal, cli)
+	scanner := NewScan(context.Background(), transactionDal, subscribeDal, cli, startAt, 10*time.Millisecond, opts...)
+	t.Cleanup(func() { scanner.Stop() })
+	return &scanFixture{node: node, parser: parser, scanner: scanner, subscribeDal: subscribeDal, transactionDal: transactionDal}
+}
+
+// waitForBlock waits until the scanner scanned the given block.
+func (f *scanFixture) waitForBlock(t *testing.T, number int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for f.scanner.GetCurrentBlock() < number {
+		if time.Now().After(deadline) {
+			t.Fatalf("scanner stuck at block %d, expected %d", f.scanner.GetCurrentBlock(), number)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBlockScan(t *testing.T) {
+	f := newScanFixture(t, 1)
+	ctx := context.Background()
+	f.parser.Subscribe(ctx, alice)
+
+	// Blocks behind the head are caught up with in a batch.
+	f.node.MineEmpty(3)
+	f.scanner.Run()
+	f.waitForBlock(t, 3)
+
+	// A failing node delays the scan until it recovers.
+	f.node.SetFault(ethclient.GetBlockByNumber, ethtest.Fault{
+		Error: &ethclient.RPCError{Code: ethclient.CodeInternalError, Message: "injected"},
+		Times: 2,
+	})
+	block := f.node.Mine(
+		ethtest.Tx{From: alice, To: bob, Value: big.NewInt(1)},
+		ethtest.Tx{From: bob, To: carol, Value: big.NewInt(2)},
+		ethtest.Tx{From: carol, To: alice, Value: big.NewInt(3), Failed: true},
+	)
+	f.waitForBlock(t, block.Number)
+
+	transactions := f.parser.GetTransactions(ctx, alice)
+	if len(transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(transactions))
+	}
+	expected := []struct{ hash, status string }{
+		{block.Transactions[0].Hash, types.StatusSuccess},
+		{block.Transactions[2].Hash, types.StatusFailed},
+	}
+	for i, tx := range transactions {
+		if tx.Hash != expected[i].hash || tx.Status != expected[i].status || tx.GasUsed != "0x5208" {
+			t.Errorf("unexpected transaction %d %+v", i, tx)
+		}
+	}
+	if calls := f.node.Calls(ethclient.GetBlockByNumber); calls < 3 {
+		t.Errorf("expected the block to be requested again, got %d calls", calls)
+	}
+}
+
+func TestBlockScanFinalized(t *testing.T) {
+	f := newScanFixture(t, 1, WithHeadBlock(ethclient.FinalizedBlock))
+	ctx := context.Background()
+	f.node.SetFinality(0, 2)
+	f.parser.Subscribe(ctx, alice)
+	f.node.MineEmpty(3)
+	f.scanner.Run()
+	f.waitForBlock(t, 1)
+
+	// The transfer to alice is reorganized out before being finalized.
+	dropped := f.node.Mine(ethtest.Tx{From: bob, To: alice, Value: big.NewInt(1)})
+	f.node.Reorg(1)
+	kept := f.node.Mine(ethtest.Tx{From: carol, To: alice, Value: big.NewInt(2)})
+	f.node.MineEmpty(2)
+	f.waitForBlock(t, kept.Number)
+
+	transactions := f.parser.GetTransactions(ctx, alice)
+	if len(transactions) != 1 || transactions[0].Hash != kept.Transactions[0].Hash {
+		t.Fatalf("expected only %s, got %+v", kept.Transactions[0].Hash, transactions)
+	}
+	if transactions[0].Hash == dropped.Transactions[0].Hash {
+		t.Error("reorganized transaction saved")
+	}
+	if current := f.scanner.GetCurrentBlock(); current != kept.Number {
+		t.Errorf("expected the scanner to stay at the finalized block %d, got %d", kept.Number, current)
+	}
+}
+
+func TestBlockScanAboveFinalized(t *testing.T) {
+	f := newScanFixture(t, 4, WithHeadBlock(ethclient.FinalizedBlock))
+	f.node.SetFinality(0, 3)
+	f.node.MineEmpty(5)
+	f.scanner.Run()
+
+	// The finalized block 2 is behind the start, nothing is scanned until
+	// the finalized head passes it.
+	time.Sleep(50 * time.Millisecond)
+	if current := f.scanner.GetCurrentBlock(); current != 4 {
+		t.Errorf("expected the scanner to stay at block 4, got %d", current)
+	}
+
+	f.node.MineEmpty(3)
+	f.waitForBlock(t, 5)
+}
+
+func TestBlockScanReorg(t *testing.T) {
+	// The cache was told blocks up to 3 are final, wrongly.
+	cache := ethclient.NewCache(ethclient.DefaultCacheConfig())
+	cache.SetFinalized(3)
+	f := newClientScanFixture(t, 1, []ethclient.Option{ethclient.WithInterceptors(cache.Interceptor())}, WithCache(cache))
+	ctx := context.Background()
+	f.parser.Subscribe(ctx, alice)
+	f.node.MineEmpty(3)
+	f.scanner.Run()
+	f.waitForBlock(t, 3)
+
+	// The cached block 3 is replaced, the scanner finds it reorganized from
+	// the parent of block 4 and scans it again from the node.
+	f.node.Reorg(1)
+	kept := f.node.Mine(ethtest.Tx{From: carol, To: alice, Value: big.NewInt(1)})
+	f.node.MineEmpty(1)
+	f.waitForBlock(t, kept.Number+1)
+
+	transactions := f.parser.GetTransactions(ctx, alice)
+	if len(transactions) != 1 || transactions[0].Hash != kept.Transactions[0].Hash {
+		t.Fatalf("expected %s, got %+v", kept.Transactions[0].Hash, transactions)
+	}
+}
+
+func TestBlockScanReorgRecords(t *testing.T) {
+	f := newScanFixture(t, 1)
+	ctx := context.Background()
+	f.parser.Subscribe(ctx, alice)
+	f.node.MineEmpty(1)
+	f.scanner.Run()
+	f.waitForBlock(t, 1)
+
+	// Both transfers of the orphaned block touch alice, only the first one
+	// is included again.
+	sent := ethtest.Tx{From: alice, To: bob, Value: big.NewInt(1)}
+	orphaned := f.node.Mine(sent, ethtest.Tx{From: carol, To: alice, Value: big.NewInt(2)})
+	f.waitForBlock(t, orphaned.Number)
+	f.node.Reorg(1)
+	kept := f.node.Mine(sent, ethtest.Tx{From: bob, To: alice, Value: big.NewInt(3)})
+	f.node.MineEmpty(1)
+	f.waitForBlock(t, kept.Number+1)
+
+	if kept.Transactions[0].Hash != orphaned.Transactions[0].Hash {
+		t.Fatal("expected the transfer of alice to be included again")
+	}
+	transactions := f.parser.GetTransactions(ctx, alice)
+	if len(transactions) != 2 ||
+		transactions[0].Hash != kept.Transactions[0].Hash || transactions[1].Hash != kept.Transactions[1].Hash {
+		t.Fatalf("expected the transactions of the canonical block only, got %+v", transactions)
+	}
+}
+
+func TestBlockScanChainID(t *testing.T) {
+	f := newScanFixture(t, 1, WithChainID(ethtest.DefaultChainID))
+	ctx := context.Background()
+	f.parser.Subscribe(ctx, alice)
+	f.node.MineEmpty(1)
+	f.scanner.Run()
+	f.waitForBlock(t, 1)
+
+	// The node switches to a testnet, its transactions are not saved.
+	f.node.SetChainID(5)
+	f.node.Mine(ethtest.Tx{From: alice, To: bob, Value: big.NewInt(1)})
+	deadline := time.Now().Add(5 * time.Second)
+	for f.node.Calls(ethclient.GetChainID) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the chain to be verified again")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if transactions := f.parser.GetTransactions(ctx, alice); len(transactions) != 0 {
+		t.Errorf("expected no transactions, got %+v", transactions)
+	}
+	if current := f.scanner.GetCurrentBlock(); current != 1 {
+		t.Errorf("expected the scanner to stay at block 1, got %d", current)
+	}
+}
+
+func TestBlockScanForeignTransaction(t *testing.T) {
+	f := newScanFixture(t, 0, WithChainID(ethtest.DefaultChainID))
+	ctx := context.Background()
+	f.parser.Subscribe(ctx, alice)
+
+	// A transaction of another chain between unsubscribed addresses, after
+	// a transaction of the subscribed address.
+	f.node.Mine(
+		ethtest.Tx{From: alice, To: bob, Value: big.NewInt(1)},
+		ethtest.Tx{From: bob, To: "0x000000000000000000000000000000000000ca11", ChainID: 5},
+	)
+	f.scanner.Run()
+	deadline := time.Now().Add(5 * time.Second)
+	for f.node.Calls(ethclient.GetChainID) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the chain to be verified again")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if transactions := f.parser.GetTransactions(ctx, alice); len(transactions) != 0 {
+		t.Errorf("expected no transactions, got %+v", transactions)
+	}
+	if current := f.scanner.GetCurrentBlock(); current != 0 {
+		t.Errorf("expected the block not to be scanned, got %d", current)
+	}
+}
+
+func TestBlockScanWithdrawals(t *testing.T) {
+	f := newScanFixture(t, 0)
+	ctx := context.Background()
+	f.parser.Subscribe(ctx, alice)
+	block := f.node.MineWithdrawals(
+		ethtest.Withdrawal{ValidatorIndex: 7, Address: alice, Amount: 1500000000},
+		ethtest.Withdrawal{ValidatorIndex: 8, Address: bob, Amount: 1},
+	)
+	f.scanner.Run()
+	f.waitForBlock(t, block.Number)
+
+	transactions := f.parser.GetTransactions(ctx, alice)
+	if len(transactions) != 1 {
+		t.Fatalf("expected 1 withdrawal, got %+v", transactions)
+	}
+	withdrawal := transactions[0]
+	if withdrawal.Kind != types.KindWithdrawal || withdrawal.To != alice || withdrawal.Value != "0x14d1120d7b160000" ||
+		withdrawal.WithdrawalIndex != "0x0" || withdrawal.ValidatorIndex != "0x7" || withdrawal.BlockNumber != "0x1" {
+		t.Errorf("unexpected withdrawal %+v", withdrawal)
+	}
+}
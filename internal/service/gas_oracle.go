@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+)
+
+// GasOracleConfig configures the fee suggestions of a GasOracle.
+type GasOracleConfig struct {
+	// Blocks is the number of recent blocks whose priority fees are
+	// considered.
+	Blocks int
+	// Percentiles of the priority fees paid in every block the slow,
+	// standard and fast suggestions are based on, in ascending order.
+	Percentiles [3]float64
+	// BaseFeeMultiplier leaves room in the max fee for the base fee to
+	// rise before the transaction is included, 2 covering six full blocks.
+	BaseFeeMultiplier int64
+}
+
+// DefaultGasOracleConfig returns the gas oracle configuration used by the
+// parser.
+func DefaultGasOracleConfig() GasOracleConfig {
+	return GasOracleConfig{
+		Blocks:            20,
+		Percentiles:       [3]float64{10, 50, 90},
+		BaseFeeMultiplier: 2,
+	}
+}
+
+// FeeSuggestion is an EIP-1559 fee suggestion, in wei per gas.
+type FeeSuggestion struct {
+	MaxPriorityFeePerGas *big.Int
+	MaxFeePerGas         *big.Int
+}
+
+// GasPrices are the fee suggestions of the gas oracle.
+type GasPrices struct {
+	// BaseFee is the base fee per gas of the next block.
+	BaseFee *big.Int
+	// GasPrice is the gas price suggested by the node for legacy
+	// transactions.
+	GasPrice *big.Int
+
+	Slow     FeeSuggestion
+	Standard FeeSuggestion
+	Fast     FeeSuggestion
+}
+
+// GasOracle suggests fees from the base fee and the priority fees paid in
+// recent blocks.
+type GasOracle struct {
+	cli    *ethclient.Client
+	config GasOracleConfig
+}
+
+// NewGasOracle creates a new GasOracle instance
+func NewGasOracle(cli *ethclient.Client, config GasOracleConfig) *GasOracle {
+	return &GasOracle{cli: cli, config: config}
+}
+
+// Suggest returns the slow, standard and fast fee suggestions for the next
+// block. The priority fees are the medians over the recent blocks of the
+// configured percentiles, the node suggestion if the blocks are empty.
+func (o *GasOracle) Suggest(ctx context.Context) (*GasPrices, error) {
+	history, err := o.cli.FeeHistory(ctx, o.config.Blocks, ethclient.LatestBlock, o.config.Percentiles[:])
+	if err != nil {
+		return nil, fmt.Errorf("error querying fee history: %w", err)
+	}
+	if len(history.BaseFee) == 0 {
+		return nil, errors.New("empty fee history")
+	}
+	gasPrice, err := o.cli.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error querying gas price: %w", err)
+	}
+
+	tips := medianRewards(history)
+	if tips == nil {
+		tip, err := o.cli.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error querying priority fee: %w", err)
+		}
+		tips = []*big.Int{tip, tip, tip}
+	}
+
+	prices := &GasPrices{
+		BaseFee:  history.BaseFee[len(history.BaseFee)-1],
+		GasPrice: gasPrice,
+	}
+	for i, suggestion := range []*FeeSuggestion{&prices.Slow, &prices.Standard, &prices.Fast} {
+		suggestion.MaxPriorityFeePerGas = tips[i]
+		suggestion.MaxFeePerGas = new(big.Int).Mul(prices.BaseFee, big.NewInt(o.config.BaseFeeMultiplier))
+		suggestion.MaxFeePerGas.Add(suggestion.MaxFeePerGas, tips[i])
+	}
+	return prices, nil
+}
+
+// medianRewards returns the median over the blocks of the history of the
+// priority fees paid at every percentile. Empty blocks report no fees and
+// are skipped, it returns nil if there are only empty blocks.
+func medianRewards(history *ethclient.FeeHistory) []*big.Int {
+	var rewards [3][]*big.Int
+	for i, blockRewards := range history.Reward {
+		if (i < len(history.GasUsedRatio) && history.GasUsedRatio[i] == 0) || len(blockRewards) != len(rewards) {
+			continue
+		}
+		for j := range rewards {
+			rewards[j] = append(rewards[j], blockRewards[j])
+		}
+	}
+	if len(rewards[0]) == 0 {
+		return nil
+	}
+
+	medians := make([]*big.Int, len(rewards))
+	for i, values := range rewards {
+		sort.Slice(values, func(a, b int) bool { return values[a].Cmp(values[b]) < 0 })
+		medians[i] = values[len(values)/2]
+	}
+	return medians
+}
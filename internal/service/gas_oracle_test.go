@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+	"github.com/352174109/trustwallet-homework/pkg/ethtest"
+)
+
+// serveFees answers the fee queries with three blocks, the empty second
+// one paying no priority fees, and a next base fee of 10 gwei.
+func serveFees(node *ethtest.Node) {
+	node.HandleMethod(ethclient.GetFeeHistory, func(params []json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{
+			"oldestBlock":   "0x1",
+			"baseFeePerGas": []string{"0x1dcd65000", "0x218711a00", "0x1dcd65000", "0x2540be400"},
+			"gasUsedRatio":  []float64{0.9, 0, 0.4},
+			"reward": [][]string{
+				{"0x3b9aca00", "0x77359400", "0xb2d05e00"},
+				{"0x0", "0x0", "0x0"},
+				{"0x5f5e100", "0x3b9aca00", "0x12a05f200"},
+			},
+		}, nil
+	})
+	node.HandleMethod(ethclient.GetGasPrice, func(params []json.RawMessage) (interface{}, error) {
+		return "0x2cb417800", nil
+	})
+	node.HandleMethod(ethclient.GetMaxPriorityFeePerGas, func(params []json.RawMessage) (interface{}, error) {
+		return "0x3b9aca00", nil
+	})
+}
+
+func TestGasOracle(t *testing.T) {
+	node := ethtest.NewNode()
+	defer node.Close()
+	serveFees(node)
+	oracle := NewGasOracle(node.Client(), DefaultGasOracleConfig())
+
+	prices, err := oracle.Suggest(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if prices.BaseFee.Int64() != 10e9 || prices.GasPrice.Int64() != 12e9 {
+		t.Errorf("unexpected base fee %s and gas price %s", prices.BaseFee, prices.GasPrice)
+	}
+	// The medians of two blocks are their highest fees.
+	expected := []struct {
+		name     string
+		tip      int64
+		actual   FeeSuggestion
+		expected int64
+	}{
+		{"slow", 1e9, prices.Slow, 21e9},
+		{"standard", 2e9, prices.Standard, 22e9},
+		{"fast", 5e9, prices.Fast, 25e9},
+	}
+	for _, test := range expected {
+		if test.actual.MaxPriorityFeePerGas.Int64() != test.tip || test.actual.MaxFeePerGas.Int64() != test.expected {
+			t.Errorf("%s: unexpected suggestion %s/%s", test.name, test.actual.MaxPriorityFeePerGas, test.actual.MaxFeePerGas)
+		}
+	}
+
+	// Without recent fees the node suggestion is used.
+	node.HandleMethod(ethclient.GetFeeHistory, func(params []json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{
+			"oldestBlock":   "0x1",
+			"baseFeePerGas": []string{"0x3b9aca00", "0x3b9aca00"},
+			"gasUsedRatio":  []float64{0},
+			"reward":        [][]string{{"0x0", "0x0", "0x0"}},
+		}, nil
+	})
+	prices, err = oracle.Suggest(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if prices.Slow.MaxPriorityFeePerGas.Int64() != 1e9 || prices.Fast.MaxFeePerGas.Int64() != 3e9 {
+		t.Errorf("unexpected suggestions %+v", prices)
+	}
+}
@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/352174109/trustwallet-homework/internal/dal"
+	"github.com/352174109/trustwallet-homework/internal/logs"
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+	"github.com/352174109/trustwallet-homework/pkg/types"
+	"github.com/352174109/trustwallet-homework/pkg/utils"
+)
+
+const (
+	// maxPendingBatch is the maximum number of transactions fetched in one
+	// batch request.
+	maxPendingBatch = 100
+
+	// maxPendingPerTick is the maximum number of new pending transactions
+	// fetched per interval, the others are skipped so that a busy mempool
+	// does not exhaust the quota of the provider. Skipped transactions are
+	// still saved by the scanner once mined.
+	maxPendingPerTick = 2 * maxPendingBatch
+
+	// droppedAfterMisses is the number of consecutive checks a pending
+	// transaction must be unknown to the node before it is considered
+	// dropped, as pool endpoints do not share their mempool.
+	droppedAfterMisses = 3
+)
+
+// pendingRecord is a tracked pending transaction.
+type pendingRecord struct {
+	tx     *types.Transaction
+	nonce  uint64
+	misses int
+}
+
+// MempoolWatcher records the pending transactions of the subscribed
+// addresses, then reconciles them once they are mined, dropped from the
+// mempool or replaced by another transaction with the same nonce.
+//
+// Mined transactions are left to the scanner, saving them with their
+// receipt replaces the pending record. Dropped and replaced ones are saved
+// again with the matching status.
+type MempoolWatcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	cli            *ethclient.Client
+	pushCli        *ethclient.Client
+	transactionDal *dal.TransactionDal
+	subscribeDal   *dal.SubscribeDal
+
+	interval time.Duration
+
+	// pending holds the tracked transactions by hash, and nonces their hash
+	// by sender and nonce. Both are owned by the watching goroutine.
+	pending map[string]*pendingRecord
+	nonces  map[string]string
+
+	// filterID is the pending transaction filter polled, empty until it is
+	// created. noFilters is set once the node rejected filters.
+	filterID  string
+	noFilters bool
+
+	// budget is the number of new pending transactions which may still be
+	// fetched until the next tick.
+	budget int
+
+	once sync.Once
+}
+
+// MempoolOption configures a MempoolWatcher.
+type MempoolOption func(*MempoolWatcher)
+
+// WithPendingSubscription receives the pending transactions with the
+// newPendingTransactions notifications of cli, which must use a push
+// transport, instead of polling a filter. The watcher polls while the
+// subscription is down.
+func WithPendingSubscription(cli *ethclient.Client) MempoolOption {
+	return func(w *MempoolWatcher) {
+		w.pushCli = cli
+	}
+}
+
+// NewMempoolWatcher creates a new MempoolWatcher instance, checking the
+// pending transactions every interval.
+func NewMempoolWatcher(ctx context.Context, transactionDal *dal.TransactionDal, subscribeDal *dal.SubscribeDal, cli *ethclient.Client, interval time.Duration, opts ...MempoolOption) *MempoolWatcher {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &MempoolWatcher{
+		ctx:    ctx,
+		cancel: cancel,
+
+		cli:            cli,
+		transactionDal: transactionDal,
+		subscribeDal:   subscribeDal,
+
+		interval: interval,
+		budget:   maxPendingPerTick,
+		pending:  make(map[string]*pendingRecord),
+		nonces:   make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Run starts watching the mempool.
+func (w *MempoolWatcher) Run() {
+	w.once.Do(func() {
+		go utils.WrapRecover(w.ctx, w.run)
+	})
+}
+
+func (w *MempoolWatcher) Stop() error {
+	w.cancel()
+	return nil
+}
+
+func (w *MempoolWatcher) run() error {
+	hashes := make(chan string, maxPendingBatch)
+	sub := w.subscribePending(hashes)
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+
+	var subErrs <-chan error
+	if sub != nil {
+		subErrs = sub.Err()
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			logs.CtxInfo(w.ctx, "stopping mempool watcher")
+			w.uninstallFilter()
+			return nil
+		case hash := <-hashes:
+			w.record(w.ctx, drain(hashes, hash))
+		case err, ok := <-subErrs:
+			if !ok {
+				subErrs, sub = nil, nil
+				continue
+			}
+			logs.CtxWarn(w.ctx, "pending transaction subscription interrupted, polling until it is back: %s", err)
+		case <-ticker.C:
+			w.budget = maxPendingPerTick
+			if w.pushCli != nil && sub == nil {
+				if sub = w.subscribePending(hashes); sub != nil {
+					subErrs = sub.Err()
+				}
+			}
+			if sub == nil || sub.ID() == "" {
+				w.record(w.ctx, w.poll(w.ctx))
+			}
+			w.reconcile(w.ctx)
+		}
+	}
+}
+
+// drain returns first followed by the hashes already waiting in the
+// channel, up to a batch.
+func drain(hashes <-chan string, first string) []string {
+	batch := []string{first}
+	for len(batch) < maxPendingBatch {
+		select {
+		case hash := <-hashes:
+			batch = append(batch, hash)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// subscribePending subscribes to pending transactions if a push client is
+// configured. It returns nil if there is none or the subscription failed.
+func (w *MempoolWatcher) subscribePending(hashes chan<- string) *ethclient.ClientSubscription {
+	if w.pushCli == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(w.ctx, w.interval)
+	defer cancel()
+	sub, err := w.pushCli.SubscribePendingTransactions(ctx, hashes)
+	if err != nil {
+		logs.CtxWarn(w.ctx, "error subscribing to pending transactions, polling instead: %s", err)
+		return nil
+	}
+	logs.CtxInfo(w.ctx, "subscribed to pending transactions")
+	return sub
+}
+
+// poll returns the transactions which entered the mempool since the last
+// poll, creating the filter again if the node dropped it.
+func (w *MempoolWatcher) poll(ctx context.Context) []string {
+	if w.noFilters {
+		return nil
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.filterID == "" {
+			id, err := w.cli.NewPendingTransactionFilter(ctx)
+			var rpcErr *ethclient.RPCError
+			if errors.As(err, &rpcErr) && rpcErr.Code == ethclient.CodeMethodNotFound {
+				logs.CtxWarn(ctx, "pending transaction filters not supported by the node, the mempool is not watched")
+				w.noFilters = true
+				return nil
+			}
+			if err != nil {
+				logs.CtxWarn(ctx, "error creating pending transaction filter: %s", err)
+				return nil
+			}
+			w.filterID = id
+		}
+
+		hashes, err := w.cli.PendingTransactions(ctx, w.filterID)
+		if errors.Is(err, ethclient.ErrFilterNotFound) {
+			logs.CtxDebug(ctx, "pending transaction filter %s expired", w.filterID)
+			w.filterID = ""
+			continue
+		}
+		if err != nil {
+			logs.CtxWarn(ctx, "error polling pending transactions: %s", err)
+			return nil
+		}
+		return hashes
+	}
+	return nil
+}
+
+// uninstallFilter removes the filter from the node, if any.
+func (w *MempoolWatcher) uninstallFilter() {
+	if w.filterID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), w.interval)
+	defer cancel()
+	if _, err := w.cli.UninstallFilter(ctx, w.filterID); err != nil {
+		logs.CtxDebug(ctx, "error uninstalling pending transaction filter: %s", err)
+	}
+}
+
+// record saves the pending transactions of subscribed addresses among the
+// given ones, and marks the tracked transactions they replace. Hashes over
+// the budget of the tick are skipped.
+func (w *MempoolWatcher) record(ctx context.Context, hashes []string) {
+	if len(hashes) > w.budget {
+		logs.CtxDebug(ctx, "skipping %d pending transactions over the budget of the tick", len(hashes)-w.budget)
+		hashes = hashes[:w.budget]
+	}
+	w.budget -= len(hashes)
+	for len(hashes) > 0 {
+		n := len(hashes)
+		if n > maxPendingBatch {
+			n = maxPendingBatch
+		}
+		txs, err := w.cli.TransactionsByHash(ctx, hashes[:n])
+		if err != nil {
+			logs.CtxWarn(ctx, "error querying pending transactions: %s", err)
+			return
+		}
+		hashes = hashes[n:]
+
+		for _, tx := range txs {
+			if tx == nil || !tx.Pending() || w.pending[tx.Hash] != nil {
+				continue
+			}
+			nonce, err := strconv.ParseUint(strings.TrimPrefix(tx.Nonce, "0x"), 16, 64)
+			if err != nil {
+				continue
+			}
+
+			key := nonceKey(tx.From, nonce)
+			if replaced, ok := w.pending[w.nonces[key]]; ok {
+				w.settle(ctx, replaced, types.StatusReplaced, tx.Hash)
+			}
+			if !w.subscribeDal.Subscribed(ctx, tx.From) && !w.subscribeDal.Subscribed(ctx, tx.To) {
+				continue
+			}
+
+			record := &pendingRecord{
+				tx: &types.Transaction{
+					ChainID:  tx.ChainId,
+					Hash:     tx.Hash,
+					Nonce:    tx.Nonce,
+					From:     tx.From,
+					To:       tx.To,
+					Value:    tx.Value,
+					Gas:      tx.Gas,
+					GasPrice: tx.GasPrice,
+					Input:    tx.Input,
+					Status:   types.StatusPending,
+				},
+				nonce: nonce,
+			}
+			w.pending[tx.Hash] = record
+			w.nonces[key] = tx.Hash
+			logs.CtxDebug(ctx, "pending transaction %s recorded", tx.Hash)
+			w.save(ctx, record.tx)
+		}
+	}
+}
+
+// reconcile checks the tracked transactions, which stop being tracked once
+// mined, dropped or replaced.
+func (w *MempoolWatcher) reconcile(ctx context.Context) {
+	if len(w.pending) == 0 {
+		return
+	}
+
+	hashes := make([]string, 0, len(w.pending))
+	for hash := range w.pending {
+		hashes = append(hashes, hash)
+	}
+	for len(hashes) > 0 {
+		n := len(hashes)
+		if n > maxPendingBatch {
+			n = maxPendingBatch
+		}
+		txs, err := w.cli.TransactionsByHash(ctx, hashes[:n])
+		if err != nil {
+			logs.CtxWarn(ctx, "error checking pending transactions: %s", err)
+			return
+		}
+
+		for i, tx := range txs {
+			record := w.pending[hashes[i]]
+			switch {
+			case tx != nil && !tx.Pending():
+				// The scanner saves it with its receipt.
+				logs.CtxDebug(ctx, "pending transaction %s mined in block %s", tx.Hash, tx.BlockNumber)
+				w.untrack(record)
+			case tx != nil:
+				record.misses = 0
+			default:
+				record.misses++
+				if record.misses >= droppedAfterMisses {
+					w.settleMissing(ctx, record)
+				}
+			}
+		}
+		hashes = hashes[n:]
+	}
+}
+
+// settleMissing marks a transaction the node no longer knows as replaced
+// if its nonce was used by another transaction, dropped otherwise.
+func (w *MempoolWatcher) settleMissing(ctx context.Context, record *pendingRecord) {
+	nonce, err := w.cli.NonceAt(ctx, record.tx.From, ethclient.LatestBlock)
+	if err != nil {
+		logs.CtxWarn(ctx, "error querying nonce of %s: %s", record.tx.From, err)
+		return
+	}
+	if nonce > record.nonce {
+		w.settle(ctx, record, types.StatusReplaced, "")
+		return
+	}
+	w.settle(ctx, record, types.StatusDropped, "")
+}
+
+// settle saves the final status of a tracked transaction and stops
+// tracking it.
+func (w *MempoolWatcher) settle(ctx context.Context, record *pendingRecord, status, replacedBy string) {
+	logs.CtxInfo(ctx, "pending transaction %s %s", record.tx.Hash, status)
+	w.untrack(record)
+
+	settled := *record.tx
+	settled.Status = status
+	settled.ReplacedBy = replacedBy
+	w.save(ctx, &settled)
+}
+
+func (w *MempoolWatcher) untrack(record *pendingRecord) {
+	delete(w.pending, record.tx.Hash)
+	key := nonceKey(record.tx.From, record.nonce)
+	if w.nonces[key] == record.tx.Hash {
+		delete(w.nonces, key)
+	}
+}
+
+// save saves the record for its subscribed addresses.
+func (w *MempoolWatcher) save(ctx context.Context, tx *types.Transaction) {
+	for _, addr := range []string{tx.From, tx.To} {
+		if w.subscribeDal.Subscribed(ctx, addr) {
+			w.transactionDal.SaveTransaction(ctx, addr, []*types.Transaction{tx})
+		}
+	}
+}
+
+func nonceKey(from string, nonce uint64) string {
+	return from + "/" + strconv.FormatUint(nonce, 10)
+}
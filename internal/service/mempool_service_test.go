@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+	"github.com/352174109/trustwallet-homework/pkg/ethtest"
+	"github.com/352174109/trustwallet-homework/pkg/types"
+)
+
+func TestMempoolWatcher(t *testing.T) {
+	f := newScanFixture(t, 0)
+	ctx := context.Background()
+	watcher := NewMempoolWatcher(ctx, f.transactionDal, f.subscribeDal, f.node.Client(), time.Second)
+	tick := func() {
+		watcher.record(ctx, watcher.poll(ctx))
+		watcher.reconcile(ctx)
+	}
+	expect := func(step string, expected []types.Transaction) {
+		t.Helper()
+		transactions := f.parser.GetTransactions(ctx, alice)
+		if len(transactions) != len(expected) {
+			t.Fatalf("%s: expected %d transactions, got %+v", step, len(expected), transactions)
+		}
+		for i, tx := range transactions {
+			if tx.Hash != expected[i].Hash || tx.Status != expected[i].Status || tx.ReplacedBy != expected[i].ReplacedBy {
+				t.Errorf("%s: unexpected transaction %d %+v", step, i, tx)
+			}
+		}
+	}
+
+	tick()
+	f.parser.Subscribe(ctx, alice)
+	sent := f.node.Send(
+		ethtest.Tx{From: alice, To: bob, Value: big.NewInt(1)},
+		ethtest.Tx{From: carol, To: bob, Value: big.NewInt(2)},
+		ethtest.Tx{From: bob, To: alice, Value: big.NewInt(3)},
+	)
+	tick()
+	expect("sent", []types.Transaction{
+		{Hash: sent[0].Hash, Status: types.StatusPending},
+		{Hash: sent[2].Hash, Status: types.StatusPending},
+	})
+
+	// The filter expires, alice cancels her transfer and bob's is evicted.
+	f.node.SetFault(ethclient.GetFilterChanges, ethtest.Fault{
+		Error: &ethclient.RPCError{Code: ethclient.CodeServerError, Message: "filter not found"},
+		Times: 1,
+	})
+	tick()
+	replacement := f.node.Replace(sent[0].Hash, ethtest.Tx{From: alice, To: carol})
+	f.node.Drop(sent[2].Hash)
+	for i := 0; i < droppedAfterMisses; i++ {
+		tick()
+	}
+	expect("replaced", []types.Transaction{
+		{Hash: sent[0].Hash, Status: types.StatusReplaced, ReplacedBy: replacement.Hash},
+		{Hash: replacement.Hash, Status: types.StatusPending},
+		{Hash: sent[2].Hash, Status: types.StatusDropped},
+	})
+	if calls := f.node.Calls(ethclient.NewPendingTransactionFilter); calls != 2 {
+		t.Errorf("expected the expired filter to be created again, got %d calls", calls)
+	}
+
+	// The scanner saves the mined transactions, replacing the pending
+	// record not read yet.
+	next := f.node.Send(ethtest.Tx{From: alice, To: bob, Value: big.NewInt(4)})
+	tick()
+	block := f.node.MinePending()
+	f.scanner.Run()
+	f.waitForBlock(t, block.Number)
+	tick()
+	expect("mined", []types.Transaction{
+		{Hash: next[0].Hash, Status: types.StatusSuccess},
+		{Hash: replacement.Hash, Status: types.StatusSuccess},
+	})
+	if len(watcher.pending) != 0 {
+		t.Errorf("expected no tracked transactions, got %d", len(watcher.pending))
+	}
+}
+
+func TestMempoolWatcherBudget(t *testing.T) {
+	f := newScanFixture(t, 0)
+	ctx := context.Background()
+	watcher := NewMempoolWatcher(ctx, f.transactionDal, f.subscribeDal, f.node.Client(), time.Second)
+	f.parser.Subscribe(ctx, alice)
+	watcher.poll(ctx)
+
+	txs := make([]ethtest.Tx, maxPendingPerTick+maxPendingBatch)
+	for i := range txs {
+		txs[i] = ethtest.Tx{From: carol, To: bob, Value: big.NewInt(1)}
+	}
+	f.node.Send(txs...)
+	f.node.Send(ethtest.Tx{From: alice, To: bob, Value: big.NewInt(1)})
+	watcher.record(ctx, watcher.poll(ctx))
+
+	if calls := f.node.Calls(ethclient.GetTransactionByHash); calls != maxPendingPerTick {
+		t.Errorf("expected %d transactions fetched in the tick, got %d", maxPendingPerTick, calls)
+	}
+	if transactions := f.parser.GetTransactions(ctx, alice); len(transactions) != 0 {
+		t.Errorf("expected the transfer over the budget to be skipped, got %+v", transactions)
+	}
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"math/big"
 
 	"github.com/352174109/trustwallet-homework/internal/dal"
 	"github.com/352174109/trustwallet-homework/internal/logs"
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
 	"github.com/352174109/trustwallet-homework/pkg/types"
 )
 
@@ -13,21 +15,41 @@ type Parser interface {
 	GetCurrentBlock(ctx context.Context) int
 	// Subscribe add address to observer
 	Subscribe(ctx context.Context, address string) bool
-	// GetTransactions list of inbound or outbound transactions for an address
+	// GetTransactions list of inbound or outbound transactions for an address, pending ones with their pending status
 	GetTransactions(ctx context.Context, address string) []*types.Transaction
+	// GetBalance balance in wei of an address at a block, the latest one if block is empty
+	GetBalance(ctx context.Context, address string, block string) (*big.Int, error)
+	// GetNonce number of transactions sent by an address at a block, the latest one if block is empty
+	GetNonce(ctx context.Context, address string, block string) (uint64, error)
+	// GetTokenBalance ERC-20 balance of an address at a block, the latest one if block is empty
+	GetTokenBalance(ctx context.Context, token string, address string, block string) (*TokenBalance, error)
+	// GetGasPrice slow, standard and fast fee suggestions for the next block
+	GetGasPrice(ctx context.Context) (*GasPrices, error)
+}
+
+// TokenBalance is the balance of an address in an ERC-20 token
+type TokenBalance struct {
+	Symbol   string
+	Decimals int
+	// Balance in the smallest unit of the token
+	Balance *big.Int
 }
 
 // EthereumParser implements the Parser interface
 type EthereumParser struct {
 	subscribeDal   *dal.SubscribeDal
 	transactionDal *dal.TransactionDal
+	cli            *ethclient.Client
+	gasOracle      *GasOracle
 }
 
 // NewEthereumParser creates a new EthereumParser instance
-func NewEthereumParser(subscribeDal *dal.SubscribeDal, transactionDal *dal.TransactionDal) (Parser, error) {
+func NewEthereumParser(subscribeDal *dal.SubscribeDal, transactionDal *dal.TransactionDal, cli *ethclient.Client) (Parser, error) {
 	return &EthereumParser{
 		subscribeDal:   subscribeDal,
 		transactionDal: transactionDal,
+		cli:            cli,
+		gasOracle:      NewGasOracle(cli, DefaultGasOracleConfig()),
 	}, nil
 }
 
@@ -63,3 +85,49 @@ func (p *EthereumParser) GetTransactions(ctx context.Context, address string) []
 
 	return p.transactionDal.TransactionByAddr(ctx, address)
 }
+
+// GetBalance returns the balance in wei of the address at the given block
+func (p *EthereumParser) GetBalance(ctx context.Context, address string, block string) (*big.Int, error) {
+	blockRef, err := ethclient.ParseBlockRef(block)
+	if err != nil {
+		return nil, err
+	}
+	return p.cli.BalanceAt(ctx, address, blockRef)
+}
+
+// GetNonce returns the number of transactions sent by the address at the given block
+func (p *EthereumParser) GetNonce(ctx context.Context, address string, block string) (uint64, error) {
+	blockRef, err := ethclient.ParseBlockRef(block)
+	if err != nil {
+		return 0, err
+	}
+	return p.cli.NonceAt(ctx, address, blockRef)
+}
+
+// GetTokenBalance returns the ERC-20 balance of the address at the given block, with the token symbol and decimals
+func (p *EthereumParser) GetTokenBalance(ctx context.Context, token string, address string, block string) (*TokenBalance, error) {
+	blockRef, err := ethclient.ParseBlockRef(block)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := p.cli.TokenBalance(ctx, token, address, blockRef)
+	if err != nil {
+		return nil, err
+	}
+	decimals, err := p.cli.TokenDecimals(ctx, token, blockRef)
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := p.cli.TokenSymbol(ctx, token, blockRef)
+	if err != nil {
+		// Some early tokens have no symbol or return it as bytes32.
+		logs.CtxWarn(ctx, "Failed to get symbol of token: %s, err: %s", token, err)
+	}
+	return &TokenBalance{Symbol: symbol, Decimals: decimals, Balance: balance}, nil
+}
+
+// GetGasPrice returns the fee suggestions of the gas oracle for the next block
+func (p *EthereumParser) GetGasPrice(ctx context.Context) (*GasPrices, error) {
+	return p.gasOracle.Suggest(ctx)
+}
@@ -0,0 +1,152 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	tests := map[string]string{
+		"":                          "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		"abc":                       "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+		strings.Repeat("a", 136):    "a6c4d403279fe3e0af03729caada8374b5ca54d8065329a3ebcaeb4b60aa386e",
+		"transfer(address,uint256)": "a9059cbb2ab09eb219583f4a59a5d0623ade346d962bcd4e46b11da047c9049b",
+	}
+	for input, expected := range tests {
+		if got := hex.EncodeToString(Keccak256([]byte(input))); got != expected {
+			t.Errorf("keccak256(%q): expected %s, got %s", input, expected, got)
+		}
+	}
+
+	// Splitting the input must not change the hash.
+	long := []byte(strings.Repeat("abcdefgh", 50))
+	if !bytes.Equal(Keccak256(long), Keccak256(long[:137], long[137:])) {
+		t.Error("hash of split input differs")
+	}
+}
+
+func TestMethod(t *testing.T) {
+	if selector := hex.EncodeToString(ERC20BalanceOf.Selector()); selector != "70a08231" {
+		t.Errorf("unexpected balanceOf selector %s", selector)
+	}
+
+	data, err := ERC20BalanceOf.Pack("0x00000000219ab540356cBB839Cbe05303d7705Fa")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := "70a08231" + "00000000000000000000000000000000219ab540356cbb839cbe05303d7705fa"
+	if got := hex.EncodeToString(data); got != expected {
+		t.Errorf("unexpected call data %s", got)
+	}
+
+	values, err := ERC20BalanceOf.Unpack(mustHex("0000000000000000000000000000000000000000000000000de0b6b3a7640000"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if balance := values[0].(*big.Int); balance.String() != "1000000000000000000" {
+		t.Errorf("unexpected balance %s", balance)
+	}
+
+	if _, err := ERC20BalanceOf.Unpack(nil); err != ErrEmptyResult {
+		t.Errorf("expected ErrEmptyResult, got %v", err)
+	}
+	if _, err := ERC20BalanceOf.Pack("0x1234"); err == nil {
+		t.Error("expected error for invalid address")
+	}
+	if _, err := ERC20Allowance.Pack("0x00000000219ab540356cBB839Cbe05303d7705Fa"); err == nil {
+		t.Error("expected error for missing argument")
+	}
+}
+
+func TestPackUnpack(t *testing.T) {
+	// The example of the Solidity ABI specification for
+	// f(uint256,uint32[],bytes10,bytes).
+	m := MustNewMethod("f(uint,uint32[],bytes10,bytes)")
+	if m.Signature() != "f(uint256,uint32[],bytes10,bytes)" {
+		t.Errorf("unexpected signature %s", m.Signature())
+	}
+	data, err := m.Pack(0x123, []uint32{0x456, 0x789}, []byte("1234567890"), []byte("Hello, world!"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := "8be65246" +
+		"0000000000000000000000000000000000000000000000000000000000000123" +
+		"0000000000000000000000000000000000000000000000000000000000000080" +
+		"3132333435363738393000000000000000000000000000000000000000000000" +
+		"00000000000000000000000000000000000000000000000000000000000000e0" +
+		"0000000000000000000000000000000000000000000000000000000000000002" +
+		"0000000000000000000000000000000000000000000000000000000000000456" +
+		"0000000000000000000000000000000000000000000000000000000000000789" +
+		"000000000000000000000000000000000000000000000000000000000000000d" +
+		"48656c6c6f2c20776f726c642100000000000000000000000000000000000000"
+	if got := hex.EncodeToString(data); got != expected {
+		t.Errorf("unexpected encoding\n%s\nexpected\n%s", got, expected)
+	}
+
+	values, err := Unpack(m.Inputs, data[4:])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	decoded := []interface{}{
+		big.NewInt(0x123),
+		[]interface{}{big.NewInt(0x456), big.NewInt(0x789)},
+		[]byte("1234567890"),
+		[]byte("Hello, world!"),
+	}
+	if !reflect.DeepEqual(values, decoded) {
+		t.Errorf("unexpected decoding %v", values)
+	}
+
+	// Nested dynamic types and negative integers round trip.
+	ts := []Type{MustParseType("string[]"), MustParseType("int64"), MustParseType("bool"), MustParseType("address[2]")}
+	data, err = Pack(ts, []string{"one", "two"}, -5, true, []string{"0x00000000219ab540356cbb839cbe05303d7705fa", "0x0000000000000000000000000000000000000001"})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	values, err = Unpack(ts, data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	decoded = []interface{}{
+		[]interface{}{"one", "two"},
+		big.NewInt(-5),
+		true,
+		[]interface{}{"0x00000000219ab540356cbb839cbe05303d7705fa", "0x0000000000000000000000000000000000000001"},
+	}
+	if !reflect.DeepEqual(values, decoded) {
+		t.Errorf("unexpected decoding %v", values)
+	}
+
+	if _, err := Pack([]Type{MustParseType("uint8")}, 256); err == nil {
+		t.Error("expected out of range error")
+	}
+	if _, err := Unpack(ts, data[:100]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
+func TestUnpackRevert(t *testing.T) {
+	data := mustHex("08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"000000000000000000000000000000000000000000000000000000000000001a" +
+		"4e6f7420656e6f7567682045746865722070726f76696465642e000000000000")
+	reason, err := UnpackRevert(data)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if reason != "Not enough Ether provided." {
+		t.Errorf("unexpected reason %q", reason)
+	}
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
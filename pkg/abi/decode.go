@@ -0,0 +1,143 @@
+package abi
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var errShortData = errors.New("abi: data too short")
+
+// Unpack decodes data encoded as the tuple of the given types.
+//
+// Integers decode to *big.Int, addresses to a lowercase 0x-prefixed hex
+// string, booleans to bool, fixed and dynamic bytes to []byte, strings to
+// string, and slices and arrays to []interface{}.
+func Unpack(ts []Type, data []byte) ([]interface{}, error) {
+	return unpackTuple(ts, data)
+}
+
+func unpackTuple(ts []Type, data []byte) ([]interface{}, error) {
+	values := make([]interface{}, len(ts))
+	pos := 0
+	for i, t := range ts {
+		var err error
+		if t.dynamic() {
+			var offset int
+			offset, err = readLength(data, pos)
+			if err == nil {
+				if offset > len(data) {
+					return nil, errShortData
+				}
+				values[i], err = unpack(t, data[offset:])
+			}
+		} else {
+			if pos > len(data) {
+				return nil, errShortData
+			}
+			values[i], err = unpack(t, data[pos:])
+		}
+		if err != nil {
+			return nil, err
+		}
+		pos += t.headSize()
+	}
+	return values, nil
+}
+
+// unpack decodes a single value of type t starting at the beginning of
+// data.
+func unpack(t Type, data []byte) (interface{}, error) {
+	switch t.Kind {
+	case UintKind, IntKind:
+		word, err := readWord(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		n := new(big.Int).SetBytes(word)
+		if t.Kind == IntKind && word[0]&0x80 != 0 {
+			n.Sub(n, two256)
+		}
+		if !inRange(t, n) {
+			return nil, fmt.Errorf("abi: %s out of range for %s", n, t)
+		}
+		return n, nil
+	case AddressKind:
+		word, err := readWord(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		return "0x" + hex.EncodeToString(word[wordSize-20:]), nil
+	case BoolKind:
+		word, err := readWord(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		n := new(big.Int).SetBytes(word)
+		if n.BitLen() > 1 {
+			return nil, fmt.Errorf("abi: invalid bool %s", n)
+		}
+		return n.Sign() == 1, nil
+	case FixedBytesKind:
+		word, err := readWord(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), word[:t.Size]...), nil
+	case BytesKind, StringKind:
+		length, err := readLength(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		if wordSize+length > len(data) {
+			return nil, errShortData
+		}
+		b := append([]byte(nil), data[wordSize:wordSize+length]...)
+		if t.Kind == StringKind {
+			return string(b), nil
+		}
+		return b, nil
+	case SliceKind, ArrayKind:
+		n := t.Size
+		if t.Kind == SliceKind {
+			length, err := readLength(data, 0)
+			if err != nil {
+				return nil, err
+			}
+			// Every element takes at least a word, this bounds the
+			// allocation below by the size of the data.
+			if length > len(data)/wordSize {
+				return nil, errShortData
+			}
+			n, data = length, data[wordSize:]
+		}
+		ts := make([]Type, n)
+		for i := range ts {
+			ts[i] = *t.Elem
+		}
+		return unpackTuple(ts, data)
+	}
+	return nil, fmt.Errorf("abi: unsupported type %s", t)
+}
+
+func readWord(data []byte, pos int) ([]byte, error) {
+	if pos+wordSize > len(data) {
+		return nil, errShortData
+	}
+	return data[pos : pos+wordSize], nil
+}
+
+// readLength reads an offset or a length, rejecting values that cannot
+// index the data.
+func readLength(data []byte, pos int) (int, error) {
+	word, err := readWord(data, pos)
+	if err != nil {
+		return 0, err
+	}
+	n := new(big.Int).SetBytes(word)
+	if !n.IsInt64() || n.Int64() > int64(len(data)) {
+		return 0, errShortData
+	}
+	return int(n.Int64()), nil
+}
@@ -0,0 +1,231 @@
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/352174109/trustwallet-homework/pkg/types"
+)
+
+// wordSize is the size of an ABI slot.
+const wordSize = 32
+
+var two256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// Pack encodes values as the tuple of the given types.
+//
+// Integers accept *big.Int and Go integer types, addresses a 0x-prefixed
+// hex string or a types.Address, fixed and dynamic bytes a []byte or a
+// byte array, strings a string, and slices and arrays any Go slice or
+// array of values accepted by their element type.
+func Pack(ts []Type, values ...interface{}) ([]byte, error) {
+	if len(ts) != len(values) {
+		return nil, fmt.Errorf("abi: expected %d arguments, got %d", len(ts), len(values))
+	}
+	return packTuple(ts, values)
+}
+
+func packTuple(ts []Type, values []interface{}) ([]byte, error) {
+	headSize := 0
+	for _, t := range ts {
+		headSize += t.headSize()
+	}
+
+	var head, tail []byte
+	for i, t := range ts {
+		enc, err := pack(t, values[i])
+		if err != nil {
+			return nil, fmt.Errorf("abi: argument %d: %v", i, err)
+		}
+		if t.dynamic() {
+			head = append(head, packUint(big.NewInt(int64(headSize+len(tail))))...)
+			tail = append(tail, enc...)
+		} else {
+			head = append(head, enc...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+func pack(t Type, v interface{}) ([]byte, error) {
+	switch t.Kind {
+	case UintKind, IntKind:
+		n, err := toBig(v)
+		if err != nil {
+			return nil, err
+		}
+		return packInteger(t, n)
+	case AddressKind:
+		address, err := toAddress(v)
+		if err != nil {
+			return nil, err
+		}
+		return leftPad(address[:]), nil
+	case BoolKind:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cannot use %T as bool", v)
+		}
+		word := make([]byte, wordSize)
+		if b {
+			word[wordSize-1] = 1
+		}
+		return word, nil
+	case FixedBytesKind:
+		b, err := toBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > t.Size {
+			return nil, fmt.Errorf("%d bytes do not fit in %s", len(b), t)
+		}
+		return rightPad(b), nil
+	case BytesKind:
+		b, err := toBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		return packDynamicBytes(b), nil
+	case StringKind:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot use %T as string", v)
+		}
+		return packDynamicBytes([]byte(s)), nil
+	case SliceKind, ArrayKind:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, fmt.Errorf("cannot use %T as %s", v, t)
+		}
+		n := rv.Len()
+		if t.Kind == ArrayKind && n != t.Size {
+			return nil, fmt.Errorf("expected %d elements for %s, got %d", t.Size, t, n)
+		}
+		ts := make([]Type, n)
+		values := make([]interface{}, n)
+		for i := 0; i < n; i++ {
+			ts[i] = *t.Elem
+			values[i] = rv.Index(i).Interface()
+		}
+		enc, err := packTuple(ts, values)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == SliceKind {
+			enc = append(packUint(big.NewInt(int64(n))), enc...)
+		}
+		return enc, nil
+	}
+	return nil, fmt.Errorf("unsupported abi type %s", t)
+}
+
+func packInteger(t Type, n *big.Int) ([]byte, error) {
+	if !inRange(t, n) {
+		return nil, fmt.Errorf("%s out of range for %s", n, t)
+	}
+	if n.Sign() < 0 {
+		// Two's complement over 256 bits.
+		n = new(big.Int).Add(n, two256)
+	}
+	return packUint(n), nil
+}
+
+// inRange reports whether n fits in the integer type t.
+func inRange(t Type, n *big.Int) bool {
+	if t.Kind == UintKind {
+		return n.Sign() >= 0 && n.BitLen() <= t.Size
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+	return n.Cmp(limit) < 0 && n.Cmp(limit.Neg(limit)) >= 0
+}
+
+func packUint(n *big.Int) []byte {
+	word := make([]byte, wordSize)
+	return n.FillBytes(word)
+}
+
+func packDynamicBytes(b []byte) []byte {
+	return append(packUint(big.NewInt(int64(len(b)))), rightPad(b)...)
+}
+
+// leftPad pads b with zeros on the left to a word.
+func leftPad(b []byte) []byte {
+	word := make([]byte, wordSize)
+	copy(word[wordSize-len(b):], b)
+	return word
+}
+
+// rightPad pads b with zeros on the right to a multiple of the word size.
+func rightPad(b []byte) []byte {
+	size := (len(b) + wordSize - 1) / wordSize * wordSize
+	padded := make([]byte, size)
+	copy(padded, b)
+	return padded
+}
+
+func toBig(v interface{}) (*big.Int, error) {
+	switch n := v.(type) {
+	case *big.Int:
+		if n == nil {
+			return nil, fmt.Errorf("nil integer")
+		}
+		return n, nil
+	case big.Int:
+		return &n, nil
+	case int:
+		return big.NewInt(int64(n)), nil
+	case int8:
+		return big.NewInt(int64(n)), nil
+	case int16:
+		return big.NewInt(int64(n)), nil
+	case int32:
+		return big.NewInt(int64(n)), nil
+	case int64:
+		return big.NewInt(n), nil
+	case uint:
+		return new(big.Int).SetUint64(uint64(n)), nil
+	case uint8:
+		return new(big.Int).SetUint64(uint64(n)), nil
+	case uint16:
+		return new(big.Int).SetUint64(uint64(n)), nil
+	case uint32:
+		return new(big.Int).SetUint64(uint64(n)), nil
+	case uint64:
+		return new(big.Int).SetUint64(n), nil
+	}
+	return nil, fmt.Errorf("cannot use %T as integer", v)
+}
+
+func toAddress(v interface{}) (types.Address, error) {
+	switch a := v.(type) {
+	case types.Address:
+		return a, nil
+	case *types.Address:
+		return *a, nil
+	case string:
+		var address types.Address
+		b, err := hex.DecodeString(strings.TrimPrefix(a, "0x"))
+		if err != nil || len(b) != types.AddressLength {
+			return address, fmt.Errorf("invalid address %q", a)
+		}
+		address.SetBytes(b)
+		return address, nil
+	}
+	return types.Address{}, fmt.Errorf("cannot use %T as address", v)
+}
+
+func toBytes(v interface{}) ([]byte, error) {
+	if b, ok := v.([]byte); ok {
+		return b, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return b, nil
+	}
+	return nil, fmt.Errorf("cannot use %T as bytes", v)
+}
@@ -0,0 +1,139 @@
+package abi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Method is a contract function, identified by its signature.
+type Method struct {
+	Name    string
+	Inputs  []Type
+	Outputs []Type
+}
+
+// NewMethod parses a function signature such as "balanceOf(address)"
+// along with the types it returns.
+func NewMethod(signature string, outputs ...string) (Method, error) {
+	signature = strings.ReplaceAll(signature, " ", "")
+	open := strings.Index(signature, "(")
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return Method{}, fmt.Errorf("invalid method signature %q", signature)
+	}
+
+	m := Method{Name: signature[:open]}
+	if args := signature[open+1 : len(signature)-1]; args != "" {
+		for _, arg := range strings.Split(args, ",") {
+			t, err := ParseType(arg)
+			if err != nil {
+				return Method{}, err
+			}
+			m.Inputs = append(m.Inputs, t)
+		}
+	}
+	for _, output := range outputs {
+		t, err := ParseType(output)
+		if err != nil {
+			return Method{}, err
+		}
+		m.Outputs = append(m.Outputs, t)
+	}
+	return m, nil
+}
+
+// MustNewMethod is like NewMethod but panics on error. It is meant for
+// package level declarations.
+func MustNewMethod(signature string, outputs ...string) Method {
+	m, err := NewMethod(signature, outputs...)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Signature returns the canonical signature of the method.
+func (m Method) Signature() string {
+	names := make([]string, len(m.Inputs))
+	for i, t := range m.Inputs {
+		names[i] = t.String()
+	}
+	return m.Name + "(" + strings.Join(names, ",") + ")"
+}
+
+// Selector returns the first 4 bytes of the hash of the signature, which
+// prefix the call data.
+func (m Method) Selector() []byte {
+	return Keccak256([]byte(m.Signature()))[:4]
+}
+
+// Pack returns the call data of the method invoked with args.
+func (m Method) Pack(args ...interface{}) ([]byte, error) {
+	if len(args) != len(m.Inputs) {
+		return nil, fmt.Errorf("abi: %s expects %d arguments, got %d", m.Name, len(m.Inputs), len(args))
+	}
+	enc, err := packTuple(m.Inputs, args)
+	if err != nil {
+		return nil, err
+	}
+	return append(m.Selector(), enc...), nil
+}
+
+// Unpack decodes the return data of the method.
+func (m Method) Unpack(data []byte) ([]interface{}, error) {
+	if len(data) == 0 && len(m.Outputs) > 0 {
+		// Calls to accounts without code succeed with empty data.
+		return nil, ErrEmptyResult
+	}
+	return unpackTuple(m.Outputs, data)
+}
+
+// ErrEmptyResult is returned when decoding the empty result of a method
+// that returns values, e.g. when the called address is not a contract.
+var ErrEmptyResult = errors.New("abi: empty result")
+
+// Common view functions of ERC-20 and ERC-721 contracts.
+var (
+	ERC20Name        = MustNewMethod("name()", "string")
+	ERC20Symbol      = MustNewMethod("symbol()", "string")
+	ERC20Decimals    = MustNewMethod("decimals()", "uint8")
+	ERC20TotalSupply = MustNewMethod("totalSupply()", "uint256")
+	ERC20BalanceOf   = MustNewMethod("balanceOf(address)", "uint256")
+	ERC20Allowance   = MustNewMethod("allowance(address,address)", "uint256")
+
+	ERC721OwnerOf           = MustNewMethod("ownerOf(uint256)", "address")
+	ERC721TokenURI          = MustNewMethod("tokenURI(uint256)", "string")
+	ERC721GetApproved       = MustNewMethod("getApproved(uint256)", "address")
+	ERC721IsApprovedForAll  = MustNewMethod("isApprovedForAll(address,address)", "bool")
+	ERC165SupportsInterface = MustNewMethod("supportsInterface(bytes4)", "bool")
+)
+
+var (
+	revertError = MustNewMethod("Error(string)", "string")
+	revertPanic = MustNewMethod("Panic(uint256)", "uint256")
+)
+
+// UnpackRevert decodes the reason of a reverted call from its return
+// data, either an Error(string) message or a Panic(uint256) code.
+func UnpackRevert(data []byte) (string, error) {
+	if len(data) < 4 {
+		return "", errors.New("abi: no revert reason")
+	}
+	switch {
+	case bytes.Equal(data[:4], revertError.Selector()):
+		values, err := unpackTuple(revertError.Outputs, data[4:])
+		if err != nil {
+			return "", err
+		}
+		return values[0].(string), nil
+	case bytes.Equal(data[:4], revertPanic.Selector()):
+		values, err := unpackTuple(revertPanic.Outputs, data[4:])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("panic code 0x%x", values[0].(*big.Int)), nil
+	}
+	return "", errors.New("abi: unknown revert reason")
+}
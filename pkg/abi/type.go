@@ -0,0 +1,138 @@
+package abi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is the category of an ABI type.
+type Kind int
+
+const (
+	UintKind Kind = iota
+	IntKind
+	AddressKind
+	BoolKind
+	FixedBytesKind
+	BytesKind
+	StringKind
+	SliceKind
+	ArrayKind
+)
+
+// Type is a parsed ABI type. Tuples are not supported.
+type Type struct {
+	Kind Kind
+	// Size is the bit size of integers, the byte size of fixed bytes and
+	// the length of fixed arrays.
+	Size int
+	// Elem is the element type of slices and arrays.
+	Elem *Type
+
+	name string
+}
+
+// ParseType parses a canonical ABI type such as uint256, address, bytes32,
+// string or address[].
+func ParseType(s string) (Type, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "]") {
+		open := strings.LastIndex(s, "[")
+		if open < 0 {
+			return Type{}, fmt.Errorf("invalid abi type %q", s)
+		}
+		elem, err := ParseType(s[:open])
+		if err != nil {
+			return Type{}, err
+		}
+		length := s[open+1 : len(s)-1]
+		if length == "" {
+			return Type{Kind: SliceKind, Elem: &elem, name: s}, nil
+		}
+		n, err := strconv.Atoi(length)
+		if err != nil || n <= 0 {
+			return Type{}, fmt.Errorf("invalid array length in abi type %q", s)
+		}
+		return Type{Kind: ArrayKind, Size: n, Elem: &elem, name: s}, nil
+	}
+
+	switch {
+	case s == "address":
+		return Type{Kind: AddressKind, Size: 20, name: s}, nil
+	case s == "bool":
+		return Type{Kind: BoolKind, name: s}, nil
+	case s == "string":
+		return Type{Kind: StringKind, name: s}, nil
+	case s == "bytes":
+		return Type{Kind: BytesKind, name: s}, nil
+	case strings.HasPrefix(s, "bytes"):
+		n, err := strconv.Atoi(s[len("bytes"):])
+		if err != nil || n < 1 || n > 32 {
+			return Type{}, fmt.Errorf("invalid abi type %q", s)
+		}
+		return Type{Kind: FixedBytesKind, Size: n, name: s}, nil
+	case strings.HasPrefix(s, "uint"):
+		n, err := parseIntSize(s[len("uint"):])
+		if err != nil {
+			return Type{}, fmt.Errorf("invalid abi type %q", s)
+		}
+		return Type{Kind: UintKind, Size: n, name: "uint" + strconv.Itoa(n)}, nil
+	case strings.HasPrefix(s, "int"):
+		n, err := parseIntSize(s[len("int"):])
+		if err != nil {
+			return Type{}, fmt.Errorf("invalid abi type %q", s)
+		}
+		return Type{Kind: IntKind, Size: n, name: "int" + strconv.Itoa(n)}, nil
+	}
+	return Type{}, fmt.Errorf("unsupported abi type %q", s)
+}
+
+// MustParseType is like ParseType but panics on error. It is meant for
+// package level declarations.
+func MustParseType(s string) Type {
+	t, err := ParseType(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// parseIntSize parses the bit size of an integer type, uint alone is an
+// alias for uint256.
+func parseIntSize(s string) (int, error) {
+	if s == "" {
+		return 256, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 8 || n > 256 || n%8 != 0 {
+		return 0, fmt.Errorf("invalid integer size %q", s)
+	}
+	return n, nil
+}
+
+// String returns the canonical name of the type, as used in signatures.
+func (t Type) String() string {
+	return t.name
+}
+
+// dynamic reports whether the encoding of the type is referenced by an
+// offset in the head of the enclosing tuple.
+func (t Type) dynamic() bool {
+	switch t.Kind {
+	case BytesKind, StringKind, SliceKind:
+		return true
+	case ArrayKind:
+		return t.Elem.dynamic()
+	}
+	return false
+}
+
+// headSize is the number of bytes the type takes in the head of the
+// enclosing tuple.
+func (t Type) headSize() int {
+	if t.Kind == ArrayKind && !t.dynamic() {
+		return t.Size * t.Elem.headSize()
+	}
+	return wordSize
+}
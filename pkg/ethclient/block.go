@@ -0,0 +1,135 @@
+package ethclient
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Block tags accepted in place of a block number. Safe and finalized
+// follow the consensus layer, they lag the latest block by a few minutes
+// but are not reorganized, or in the case of safe only very unlikely.
+const (
+	TagLatest    = "latest"
+	TagSafe      = "safe"
+	TagFinalized = "finalized"
+	TagPending   = "pending"
+	TagEarliest  = "earliest"
+)
+
+// BlockRef identifies the block a query is run against, by number, by tag
+// or by hash as defined by EIP-1898. The zero value is the latest block.
+type BlockRef struct {
+	number    int
+	hasNumber bool
+	tag       string
+
+	hash string
+	// requireCanonical makes the node fail queries by hash on blocks that
+	// are no longer part of the canonical chain.
+	requireCanonical bool
+}
+
+var (
+	LatestBlock    = BlockRef{tag: TagLatest}
+	SafeBlock      = BlockRef{tag: TagSafe}
+	FinalizedBlock = BlockRef{tag: TagFinalized}
+	PendingBlock   = BlockRef{tag: TagPending}
+	EarliestBlock  = BlockRef{tag: TagEarliest}
+)
+
+// BlockNumberRef returns a reference to the block with the given number.
+func BlockNumberRef(number int) BlockRef {
+	return BlockRef{number: number, hasNumber: true}
+}
+
+// BlockHashRef returns a reference to the block with the given hash. With
+// requireCanonical, queries fail if the block was reorganized out of the
+// chain instead of running against a stale state.
+func BlockHashRef(hash string, requireCanonical bool) BlockRef {
+	return BlockRef{hash: hash, requireCanonical: requireCanonical}
+}
+
+// ParseBlockRef parses a block tag, a decimal or a 0x-prefixed block
+// number, or a 0x-prefixed 32 bytes block hash.
+func ParseBlockRef(s string) (BlockRef, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "", TagLatest:
+		return LatestBlock, nil
+	case TagSafe:
+		return SafeBlock, nil
+	case TagFinalized:
+		return FinalizedBlock, nil
+	case TagPending:
+		return PendingBlock, nil
+	case TagEarliest:
+		return EarliestBlock, nil
+	}
+
+	if strings.HasPrefix(s, "0x") && len(s) == 66 {
+		if _, err := parseHexBytes(s); err != nil {
+			return BlockRef{}, fmt.Errorf("invalid block hash %q: %v", s, err)
+		}
+		return BlockHashRef(s, false), nil
+	}
+	if strings.HasPrefix(s, "0x") {
+		n, err := parseHexInt(s)
+		if err != nil {
+			return BlockRef{}, fmt.Errorf("invalid block %q: %v", s, err)
+		}
+		return BlockNumberRef(n), nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return BlockRef{}, fmt.Errorf("invalid block %q", s)
+	}
+	return BlockNumberRef(n), nil
+}
+
+// Number returns the block number, ok is false for tags and hashes.
+func (r BlockRef) Number() (number int, ok bool) {
+	return r.number, r.hasNumber
+}
+
+// Hash returns the block hash, ok is false for numbers and tags.
+func (r BlockRef) Hash() (hash string, ok bool) {
+	return r.hash, r.hash != ""
+}
+
+// Tag returns the block tag, latest for the zero value. It is empty for
+// numbers and hashes.
+func (r BlockRef) Tag() string {
+	if r.hasNumber || r.hash != "" {
+		return ""
+	}
+	if r.tag != "" {
+		return r.tag
+	}
+	return TagLatest
+}
+
+func (r BlockRef) String() string {
+	switch {
+	case r.hasNumber:
+		return strconv.Itoa(r.number)
+	case r.hash != "":
+		return r.hash
+	}
+	return r.Tag()
+}
+
+// arg returns the JSON-RPC parameter for the block, hashes are sent as an
+// EIP-1898 object.
+func (r BlockRef) arg() interface{} {
+	switch {
+	case r.hasNumber:
+		return toBlockNumArg(r.number)
+	case r.hash != "":
+		return map[string]interface{}{
+			"blockHash":        r.hash,
+			"requireCanonical": r.requireCanonical,
+		}
+	}
+	return r.Tag()
+}
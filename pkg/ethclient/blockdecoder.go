@@ -0,0 +1,273 @@
+package ethclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// TransactionSummary holds the fields of a transaction read by the
+// filtered block queries before decoding it, as encoded by the node.
+type TransactionSummary struct {
+	// Index is the position of the transaction in the block.
+	Index int
+	From  string
+	// To is empty for contract creations.
+	To string
+	// ChainID is empty for legacy transactions without replay protection.
+	ChainID string
+}
+
+// TransactionFilter selects the transactions decoded by the filtered block
+// queries.
+type TransactionFilter func(tx TransactionSummary) bool
+
+// BlockByNumberFiltered returns the block with the given number including
+// only the transactions accepted by filter. The transactions array is
+// tokenized and the summary of every transaction is checked before it is
+// decoded, so rejected transactions cost almost no allocation. A nil
+// filter keeps all transactions.
+func (c *Client) BlockByNumberFiltered(ctx context.Context, blockNumber int, filter TransactionFilter) (*ETHBlock, error) {
+	if filter == nil {
+		return c.BlockByNumber(ctx, blockNumber)
+	}
+
+	var block *ETHBlock
+	result := &filteredBlock{filter: filter, block: &block}
+	if err := c.callBlock(ctx, result, BlockNumberRef(blockNumber), true); err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, ErrBlockNotFound
+	}
+	return block, nil
+}
+
+// BlocksByNumberFiltered is BlocksByNumber including only the transactions
+// accepted by filter, see BlockByNumberFiltered.
+func (c *Client) BlocksByNumberFiltered(ctx context.Context, from, to int, filter TransactionFilter) ([]*ETHBlock, error) {
+	return c.blocksByNumber(ctx, from, to, filter)
+}
+
+// filteredBlock decodes a block into block, keeping the transactions
+// accepted by filter.
+type filteredBlock struct {
+	filter TransactionFilter
+	block  **ETHBlock
+}
+
+// blockFields has the fields of ETHBlock, its transactions being shadowed
+// when decoding a filteredBlock.
+type blockFields ETHBlock
+
+func (b *filteredBlock) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*b.block = nil
+		return nil
+	}
+
+	var block struct {
+		blockFields
+		Transactions filteredTransactions `json:"transactions"`
+	}
+	block.Transactions.filter = b.filter
+	if err := json.Unmarshal(data, &block); err != nil {
+		return err
+	}
+	decoded := ETHBlock(block.blockFields)
+	decoded.Transactions = block.Transactions.txs
+	*b.block = &decoded
+	return nil
+}
+
+// filteredTransactions decodes a transactions array, keeping the
+// transactions accepted by filter.
+type filteredTransactions struct {
+	filter TransactionFilter
+	txs    []*ETHTransaction
+}
+
+func (t *filteredTransactions) UnmarshalJSON(data []byte) error {
+	i := skipSpace(data, 0)
+	if bytes.HasPrefix(data[i:], []byte("null")) {
+		return nil
+	}
+	if i == len(data) || data[i] != '[' {
+		return errors.New("transactions: expected array")
+	}
+
+	t.txs = []*ETHTransaction{}
+	for index := 0; ; index++ {
+		i = skipSpace(data, i+1)
+		if i < len(data) && data[i] == ']' && index == 0 {
+			return nil
+		}
+		start := i
+		fields, end, err := scanTransaction(data, i)
+		if err != nil {
+			return fmt.Errorf("transactions: %v", err)
+		}
+		summary := TransactionSummary{
+			Index:   index,
+			From:    string(fields[summaryFrom]),
+			To:      string(fields[summaryTo]),
+			ChainID: string(fields[summaryChainID]),
+		}
+		if t.filter(summary) {
+			tx := &ETHTransaction{}
+			if err := json.Unmarshal(data[start:end], tx); err != nil {
+				return err
+			}
+			t.txs = append(t.txs, tx)
+		}
+
+		i = skipSpace(data, end)
+		if i == len(data) {
+			return errors.New("transactions: unexpected end of array")
+		}
+		switch data[i] {
+		case ',':
+		case ']':
+			return nil
+		default:
+			return fmt.Errorf("transactions: unexpected %q after element", data[i])
+		}
+	}
+}
+
+// Top-level string fields of a transaction read by scanTransaction.
+const (
+	summaryFrom = iota
+	summaryTo
+	summaryChainID
+	summaryFields
+)
+
+// scanTransaction scans the transaction object starting at data[i] and
+// returns its top-level from, to and chainId strings, without quotes, and
+// the offset following the object.
+func scanTransaction(data []byte, i int) (fields [summaryFields][]byte, end int, err error) {
+	if i == len(data) || data[i] != '{' {
+		return fields, 0, errors.New("expected transaction object")
+	}
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == '}' {
+		return fields, i + 1, nil
+	}
+	for {
+		if i == len(data) || data[i] != '"' {
+			return fields, 0, errors.New("expected object key")
+		}
+		keyEnd, err := skipString(data, i)
+		if err != nil {
+			return fields, 0, err
+		}
+		key := data[i+1 : keyEnd-1]
+		i = skipSpace(data, keyEnd)
+		if i == len(data) || data[i] != ':' {
+			return fields, 0, errors.New("expected colon after object key")
+		}
+		i = skipSpace(data, i+1)
+		valueEnd, err := skipValue(data, i)
+		if err != nil {
+			return fields, 0, err
+		}
+		if data[i] == '"' {
+			switch string(key) {
+			case "from":
+				fields[summaryFrom] = data[i+1 : valueEnd-1]
+			case "to":
+				fields[summaryTo] = data[i+1 : valueEnd-1]
+			case "chainId":
+				fields[summaryChainID] = data[i+1 : valueEnd-1]
+			}
+		}
+
+		i = skipSpace(data, valueEnd)
+		if i == len(data) {
+			return fields, 0, errors.New("unexpected end of object")
+		}
+		switch data[i] {
+		case ',':
+			i = skipSpace(data, i+1)
+		case '}':
+			return fields, i + 1, nil
+		default:
+			return fields, 0, fmt.Errorf("unexpected %q in object", data[i])
+		}
+	}
+}
+
+// skipSpace returns the offset of the first non-whitespace byte of data
+// from i.
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// skipString returns the offset following the string starting at data[i].
+func skipString(data []byte, i int) (int, error) {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, errors.New("unterminated string")
+}
+
+// skipValue returns the offset following the value starting at data[i].
+// The input was validated by encoding/json, only its structure is
+// followed.
+func skipValue(data []byte, i int) (int, error) {
+	if i == len(data) {
+		return 0, errors.New("expected value")
+	}
+	switch data[i] {
+	case '"':
+		return skipString(data, i)
+	case '{', '[':
+		depth := 0
+		for i < len(data) {
+			switch data[i] {
+			case '"':
+				end, err := skipString(data, i)
+				if err != nil {
+					return 0, err
+				}
+				i = end
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+			i++
+		}
+		return 0, errors.New("unterminated value")
+	default:
+		// Numbers and literals end at the next delimiter.
+		for i < len(data) {
+			switch data[i] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				return i, nil
+			}
+			i++
+		}
+		return i, nil
+	}
+}
@@ -0,0 +1,194 @@
+package ethclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// mainnetBlockTxs is the number of transactions of a typical mainnet block.
+const mainnetBlockTxs = 180
+
+// recordedBlock returns the block recorded with its transactions in the
+// mainnet cassette of the test package, as served by the node.
+func recordedBlock(t testing.TB) []byte {
+	cassette, err := LoadCassette("../../test/testdata/mainnet.json")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, interaction := range cassette.Interactions {
+		var response struct {
+			Result json.RawMessage `json:"result"`
+		}
+		if json.Unmarshal(interaction.Response, &response) != nil || !bytes.Contains(response.Result, []byte(`"transactions"`)) {
+			continue
+		}
+		return response.Result
+	}
+	t.Fatal("no block in the mainnet cassette")
+	return nil
+}
+
+// loadMainnetBlock returns the recorded block with its first transaction
+// repeated to mainnetBlockTxs transactions sent by distinct addresses, see
+// senderAt.
+func loadMainnetBlock(t testing.TB) []byte {
+	var block map[string]json.RawMessage
+	if err := json.Unmarshal(recordedBlock(t), &block); err != nil {
+		t.Fatal(err.Error())
+	}
+	var recorded []json.RawMessage
+	if err := json.Unmarshal(block["transactions"], &recorded); err != nil || len(recorded) == 0 {
+		t.Fatalf("no transaction in recorded block: %v", err)
+	}
+	var sender struct {
+		From string `json:"from"`
+	}
+	json.Unmarshal(recorded[0], &sender)
+
+	txs := make([]json.RawMessage, mainnetBlockTxs)
+	for i := range txs {
+		txs[i] = bytes.Replace(recorded[0], []byte(sender.From), []byte(senderAt(i)), 1)
+	}
+	block["transactions"], _ = json.Marshal(txs)
+	data, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return data
+}
+
+func senderAt(i int) string {
+	return fmt.Sprintf("0x%040x", i+1)
+}
+
+func TestFilteredBlock(t *testing.T) {
+	data := loadMainnetBlock(t)
+	var full *ETHBlock
+	if err := json.Unmarshal(data, &full); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	subscribed := map[string]bool{senderAt(7): true, senderAt(42): true}
+	filter := func(tx TransactionSummary) bool {
+		return subscribed[tx.From] || subscribed[tx.To]
+	}
+	indented, _ := json.MarshalIndent(json.RawMessage(data), "", "  ")
+	for name, data := range map[string][]byte{"compact": data, "indented": indented} {
+		var filtered *ETHBlock
+		if err := json.Unmarshal(data, &filteredBlock{filter: filter, block: &filtered}); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if len(filtered.Transactions) != 2 ||
+			!reflect.DeepEqual(filtered.Transactions[0], full.Transactions[7]) ||
+			!reflect.DeepEqual(filtered.Transactions[1], full.Transactions[42]) {
+			t.Errorf("%s: unexpected transactions %+v", name, filtered.Transactions)
+		}
+
+		header, fullHeader := *filtered, *full
+		header.Transactions, fullHeader.Transactions = nil, nil
+		if !reflect.DeepEqual(header, fullHeader) {
+			t.Errorf("%s: unexpected block %+v", name, header)
+		}
+	}
+
+	for _, test := range []struct {
+		data     string
+		expected []*ETHTransaction
+	}{
+		{`{"number":"0x1","transactions":[]}`, []*ETHTransaction{}},
+		{`{"number":"0x1","transactions":[{}, {"from":"0xa","to":null,"input":"0x\"}"}]}`, []*ETHTransaction{{From: "0xa", Input: `0x"}`}}},
+		{`{"number":"0x1","transactions":[{"accessList":[{"address":"0xa"}],"to":"0xb"},{"from":"0xc","to":"0xd"}]}`, []*ETHTransaction{}},
+		{`{"number":"0x1","transactions":[{"from":"0xc","chainId":"0x1"},{"from":"0xd","chainId":"0x5"}]}`, []*ETHTransaction{{From: "0xd", ChainId: "0x5"}}},
+	} {
+		var block *ETHBlock
+		err := json.Unmarshal([]byte(test.data), &filteredBlock{filter: func(tx TransactionSummary) bool { return tx.From == "0xa" || tx.ChainID == "0x5" }, block: &block})
+		if err != nil {
+			t.Fatalf("%s: %s", test.data, err)
+		}
+		if block.Number != "0x1" || !reflect.DeepEqual(block.Transactions, test.expected) {
+			t.Errorf("%s: unexpected transactions %+v", test.data, block.Transactions)
+		}
+	}
+
+	block := &ETHBlock{}
+	if err := json.Unmarshal([]byte("null"), &filteredBlock{filter: filter, block: &block}); err != nil || block != nil {
+		t.Errorf("unexpected block %+v for null, error %v", block, err)
+	}
+}
+
+func TestBlocksByNumberFiltered(t *testing.T) {
+	data := loadMainnetBlock(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []struct {
+			ID     int               `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&batch)
+		responses := make([]json.RawMessage, len(batch))
+		for i, req := range batch {
+			result := []byte("null")
+			if string(req.Params[0]) == `"0x1"` {
+				result = data
+			}
+			responses[i] = json.RawMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, result))
+		}
+		json.NewEncoder(w).Encode(responses)
+	}))
+	defer server.Close()
+
+	cli := NewETHClient(server.URL)
+	blocks, err := cli.BlocksByNumberFiltered(context.Background(), 1, 2, func(tx TransactionSummary) bool {
+		return tx.Index == 3
+	})
+	if !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+	if len(blocks) != 1 || len(blocks[0].Transactions) != 1 || blocks[0].Transactions[0].From != senderAt(3) {
+		t.Fatalf("unexpected blocks %+v", blocks)
+	}
+}
+
+// BenchmarkBlockDecoding compares the decoding of the recorded block in
+// full and keeping the transactions of the sender of its first transaction
+// and of the recipient of its last one.
+func BenchmarkBlockDecoding(b *testing.B) {
+	data := recordedBlock(b)
+	var block *ETHBlock
+	if err := json.Unmarshal(data, &block); err != nil {
+		b.Fatal(err.Error())
+	}
+	txs := block.Transactions
+	subscribed := map[string]bool{txs[0].From: true, txs[len(txs)-1].To: true}
+	b.Logf("block %s: %d transactions, %d bytes", block.Number, len(txs), len(data))
+
+	b.Run("Full", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var block *ETHBlock
+			if err := json.Unmarshal(data, &block); err != nil {
+				b.Fatal(err.Error())
+			}
+		}
+	})
+	b.Run("Filtered", func(b *testing.B) {
+		filter := func(tx TransactionSummary) bool {
+			return subscribed[tx.From] || subscribed[tx.To]
+		}
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var block *ETHBlock
+			if err := json.Unmarshal(data, &filteredBlock{filter: filter, block: &block}); err != nil {
+				b.Fatal(err.Error())
+			}
+		}
+	})
+}
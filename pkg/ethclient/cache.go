@@ -0,0 +1,489 @@
+package ethclient
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultCacheSize is the default maximum size of cached responses.
+	DefaultCacheSize = 64 << 20
+	// DefaultFinalityDepth is the default number of blocks below the
+	// latest block considered final, none: the finalized height is only
+	// learned from the blocks returned for the finalized tag.
+	DefaultFinalityDepth = 0
+)
+
+// CacheConfig configures a Cache.
+type CacheConfig struct {
+	// MaxBytes bounds the size of the cached results, the least recently
+	// used ones being evicted first.
+	MaxBytes int64
+	// FinalityDepth is the number of blocks below the latest block seen
+	// which are considered final, in addition to the blocks at or below
+	// the finalized block seen. 0 only relies on the finalized block.
+	FinalityDepth int
+}
+
+func DefaultCacheConfig() CacheConfig {
+	return CacheConfig{
+		MaxBytes:      DefaultCacheSize,
+		FinalityDepth: DefaultFinalityDepth,
+	}
+}
+
+// CacheStats are the counters of a Cache.
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Entries   int
+	Bytes     int64
+}
+
+// Cache keeps the results of requests for data which can no longer change:
+// blocks and transactions queried by hash, and blocks, receipts, state,
+// calls, logs and traces at or below the finalized height. Requests for
+// tags like latest are never cached, nor is the chain id, for VerifyChain
+// to see an endpoint switching networks. Use it with WithInterceptors, its
+// interceptor learns the finalized height from the responses going
+// through it, e.g. the finalized blocks polled by a scanner following
+// them. Reorgs it cannot see, like the ones of blocks it was told are
+// final, are reported with Invalidate.
+type Cache struct {
+	config CacheConfig
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+	stats   CacheStats
+	// finalized is the highest block known to be final, -1 if none.
+	finalized int
+	// hashes are the hashes of the cached blocks by number, to detect
+	// reorgs deeper than the finalized height.
+	hashes map[int]string
+}
+
+// cacheEntry is a cached result, height is the highest block it depends
+// on, -1 for results identified by the hash of their block.
+type cacheEntry struct {
+	key    string
+	result json.RawMessage
+	height int
+}
+
+func NewCache(config CacheConfig) *Cache {
+	return &Cache{
+		config:    config,
+		entries:   make(map[string]*list.Element),
+		lru:       list.New(),
+		finalized: -1,
+		hashes:    make(map[int]string),
+	}
+}
+
+// Stats returns the counters of the cache.
+func (c *Cache) Stats() CacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.size
+	return stats
+}
+
+// SetFinalized sets the finalized block number, e.g. when it is learned
+// from another client.
+func (c *Cache) SetFinalized(number int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if number > c.finalized {
+		c.finalized = number
+	}
+}
+
+// Invalidate drops the results depending on blocks from the given number,
+// after a reorg of these blocks. The finalized height is lowered below it.
+func (c *Cache) Invalidate(from int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.invalidate(from)
+}
+
+func (c *Cache) invalidate(from int) {
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		if entry := e.Value.(*cacheEntry); entry.height >= from {
+			c.remove(e)
+		}
+		e = next
+	}
+	for number := range c.hashes {
+		if number >= from {
+			delete(c.hashes, number)
+		}
+	}
+	if c.finalized >= from {
+		c.finalized = from - 1
+	}
+}
+
+func (c *Cache) get(key string) (json.RawMessage, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry).result, true
+}
+
+// store learns from the result of the call and caches it if immutable.
+func (c *Cache) store(call RequestBody, result json.RawMessage) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.observe(call, result)
+	key, ok := cacheKey(call)
+	if !ok {
+		return
+	}
+	height, ok := c.immutable(call, result)
+	if !ok {
+		return
+	}
+	size := int64(len(key) + len(result))
+	if size > c.config.MaxBytes {
+		return
+	}
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	entry := &cacheEntry{key: key, result: result, height: height}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += size
+	for c.size > c.config.MaxBytes {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) remove(e *list.Element) {
+	entry := c.lru.Remove(e).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.key) + len(entry.result))
+}
+
+// observe learns the finalized height and detects reorgs from the blocks
+// and head numbers in results.
+func (c *Cache) observe(call RequestBody, result json.RawMessage) {
+	switch call.Method {
+	case GetBlockbusterMethod:
+		var head string
+		if json.Unmarshal(result, &head) != nil || c.config.FinalityDepth <= 0 {
+			return
+		}
+		if number, err := parseHexInt(head); err == nil && number-c.config.FinalityDepth > c.finalized {
+			c.finalized = number - c.config.FinalityDepth
+		}
+	case GetBlockByNumber, GetBlockByHash:
+		var block *struct {
+			Number string `json:"number"`
+			Hash   string `json:"hash"`
+		}
+		if json.Unmarshal(result, &block) != nil || block == nil {
+			return
+		}
+		number, err := parseHexInt(block.Number)
+		if err != nil {
+			return
+		}
+		if hash, ok := c.hashes[number]; ok && hash != block.Hash {
+			c.invalidate(number)
+		}
+		if params := callParams(call); call.Method == GetBlockByNumber && len(params) > 0 && string(params[0]) == `"`+TagFinalized+`"` && number > c.finalized {
+			c.finalized = number
+		}
+	}
+}
+
+// immutable returns whether the result of the call can no longer change,
+// and the highest block it depends on.
+func (c *Cache) immutable(call RequestBody, result json.RawMessage) (int, bool) {
+	if isNull(result) {
+		return 0, false
+	}
+	params := callParams(call)
+	switch call.Method {
+	case GetBlockByHash:
+		return c.blockResult(result, true)
+	case GetUncleByBlockHashAndIndex, GetBlockTransactionCountByHash,
+		GetTransactionByBlockHashAndIndex, GetUncleCountByBlockHash:
+		// Identified by the hash of their block, they never change.
+		return -1, true
+	case GetBlockByNumber:
+		if _, ok := c.blockParam(params, 0); !ok {
+			return 0, false
+		}
+		return c.blockResult(result, false)
+	case GetBlockReceipts, GetBlockTransactionCountByNumber, GetTransactionByBlockNumberAndIndex,
+		GetUncleCountByBlockNumber, DebugTraceBlockByNumber, TraceBlock:
+		return c.blockParam(params, 0)
+	case GetBalance, GetTransactionCount, GetCode, EthCall:
+		return c.blockParam(params, 1)
+	case GetStorageAt:
+		return c.blockParam(params, 2)
+	case GetTransactionReceipt, GetTransactionByHash:
+		var tx struct {
+			BlockNumber string `json:"blockNumber"`
+		}
+		if json.Unmarshal(result, &tx) != nil {
+			return 0, false
+		}
+		number, err := parseHexInt(tx.BlockNumber)
+		if err != nil || number > c.finalized {
+			return 0, false
+		}
+		return number, true
+	case GetLogs:
+		return c.logsParam(params)
+	}
+	return 0, false
+}
+
+// blockResult returns the number of the block in result, immutable if
+// queried by hash or final.
+func (c *Cache) blockResult(result json.RawMessage, byHash bool) (int, bool) {
+	var block struct {
+		Number string `json:"number"`
+		Hash   string `json:"hash"`
+	}
+	if json.Unmarshal(result, &block) != nil {
+		return 0, false
+	}
+	number, err := parseHexInt(block.Number)
+	if err != nil || (!byHash && number > c.finalized) {
+		return 0, false
+	}
+	if number <= c.finalized {
+		c.hashes[number] = block.Hash
+	}
+	return number, true
+}
+
+// blockParam returns the height of the block parameter at index i, if it
+// references a final block by number or any block by hash.
+func (c *Cache) blockParam(params []json.RawMessage, i int) (int, bool) {
+	if i >= len(params) {
+		return 0, false
+	}
+	var ref string
+	if err := json.Unmarshal(params[i], &ref); err != nil {
+		var object struct {
+			BlockHash   string `json:"blockHash"`
+			BlockNumber string `json:"blockNumber"`
+		}
+		if err := json.Unmarshal(params[i], &object); err != nil {
+			return 0, false
+		}
+		if object.BlockHash != "" {
+			return -1, true
+		}
+		ref = object.BlockNumber
+	}
+	if len(ref) == 66 {
+		return -1, true
+	}
+	if !strings.HasPrefix(ref, "0x") {
+		return 0, false
+	}
+	number, err := parseHexInt(ref)
+	if err != nil || number > c.finalized {
+		return 0, false
+	}
+	return number, true
+}
+
+// logsParam returns the last block of an eth_getLogs filter, if final.
+func (c *Cache) logsParam(params []json.RawMessage) (int, bool) {
+	if len(params) != 1 {
+		return 0, false
+	}
+	var filter struct {
+		BlockHash string `json:"blockHash"`
+		FromBlock string `json:"fromBlock"`
+		ToBlock   string `json:"toBlock"`
+	}
+	if err := json.Unmarshal(params[0], &filter); err != nil {
+		return 0, false
+	}
+	if filter.BlockHash != "" {
+		return -1, true
+	}
+	if !strings.HasPrefix(filter.FromBlock, "0x") {
+		return 0, false
+	}
+	to, err := parseHexInt(filter.ToBlock)
+	if err != nil || to > c.finalized {
+		return 0, false
+	}
+	return to, true
+}
+
+// callParams returns the encoded params of the call.
+func callParams(call RequestBody) []json.RawMessage {
+	raw, err := json.Marshal(call.Params)
+	if err != nil {
+		return nil
+	}
+	var params []json.RawMessage
+	json.Unmarshal(raw, &params)
+	return params
+}
+
+func isNull(result json.RawMessage) bool {
+	return len(result) == 0 || bytes.Equal(result, []byte("null"))
+}
+
+// cachedMethods are the methods whose results may be cached.
+var cachedMethods = map[string]bool{
+	GetBlockByHash:                      true,
+	GetBlockByNumber:                    true,
+	GetBlockReceipts:                    true,
+	GetUncleByBlockHashAndIndex:         true,
+	GetBlockTransactionCountByNumber:    true,
+	GetBlockTransactionCountByHash:      true,
+	GetTransactionByBlockHashAndIndex:   true,
+	GetTransactionByBlockNumberAndIndex: true,
+	GetUncleCountByBlockHash:            true,
+	GetUncleCountByBlockNumber:          true,
+	DebugTraceBlockByNumber:             true,
+	TraceBlock:                          true,
+	GetBalance:                          true,
+	GetTransactionCount:                 true,
+	GetCode:                             true,
+	GetStorageAt:                        true,
+	EthCall:                             true,
+	GetTransactionReceipt:               true,
+	GetTransactionByHash:                true,
+	GetLogs:                             true,
+}
+
+// cacheKey returns the key of the call, its method and encoded params, if
+// its result may be cached.
+func cacheKey(call RequestBody) (string, bool) {
+	if !cachedMethods[call.Method] {
+		return "", false
+	}
+	params, err := json.Marshal(call.Params)
+	if err != nil {
+		return "", false
+	}
+	return call.Method + string(params), true
+}
+
+// Interceptor returns the interceptor answering requests from the cache
+// and filling it. Batches are sent without their cached elements.
+func (c *Cache) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			if !req.Batch {
+				return c.single(ctx, req, next)
+			}
+			return c.batch(ctx, req, next)
+		}
+	}
+}
+
+func (c *Cache) single(ctx context.Context, req *Request, next Handler) (*Response, error) {
+	call := req.Calls[0]
+	if key, ok := cacheKey(call); ok {
+		if result, ok := c.get(key); ok {
+			return &Response{Body: cachedResponse(call.ID, result)}, nil
+		}
+	}
+
+	resp, err := next(ctx, req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	decoded := resp.decoded
+	if decoded == nil {
+		decoded = &ResponseBody{}
+		if json.Unmarshal(resp.Body, decoded) != nil {
+			return resp, nil
+		}
+	}
+	if decoded.Error == nil {
+		c.store(call, decoded.Result)
+	}
+	return resp, nil
+}
+
+func (c *Cache) batch(ctx context.Context, req *Request, next Handler) (*Response, error) {
+	var cached []json.RawMessage
+	var missing []RequestBody
+	for _, call := range req.Calls {
+		if key, ok := cacheKey(call); ok {
+			if result, ok := c.get(key); ok {
+				cached = append(cached, cachedResponse(call.ID, result))
+				continue
+			}
+		}
+		missing = append(missing, call)
+	}
+	if len(cached) == 0 {
+		missing = req.Calls
+	}
+
+	if len(missing) > 0 {
+		forwarded := *req
+		forwarded.Calls = missing
+		resp, err := next(ctx, &forwarded)
+		if err != nil || resp == nil {
+			return resp, err
+		}
+
+		var resps []json.RawMessage
+		if err := json.Unmarshal(resp.Body, &resps); err != nil {
+			// Not a batch response, e.g. the batch was rejected as a whole.
+			return resp, nil
+		}
+		calls := make(map[int]RequestBody, len(missing))
+		for _, call := range missing {
+			calls[call.ID] = call
+		}
+		for _, raw := range resps {
+			var decoded ResponseBody
+			if json.Unmarshal(raw, &decoded) != nil || decoded.Error != nil {
+				continue
+			}
+			if call, ok := calls[decoded.ID]; ok {
+				c.store(call, decoded.Result)
+			}
+		}
+		if len(cached) == 0 {
+			return resp, nil
+		}
+		cached = append(cached, resps...)
+	}
+
+	body, err := json.Marshal(cached)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Body: body}, nil
+}
+
+func cachedResponse(id int, result json.RawMessage) []byte {
+	return []byte(fmt.Sprintf(`{"jsonrpc":"%s","id":%d,"result":%s}`, ApiVersion, id, result))
+}
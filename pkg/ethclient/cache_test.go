@@ -0,0 +1,205 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// cacheNode serves blocks and receipts, the hash of blocks depending on
+// the current fork, and counts the calls it receives.
+type cacheNode struct {
+	lock    sync.Mutex
+	calls   map[string]int
+	fork    string
+	chainID string
+}
+
+func (n *cacheNode) result(req RequestBody) string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.calls[req.Method]++
+
+	params := req.Params.([]interface{})
+	switch req.Method {
+	case GetChainID:
+		return fmt.Sprintf(`"%s"`, n.chainID)
+	case GetBlockbusterMethod:
+		return `"0x100"`
+	case GetBlockByNumber:
+		number := params[0].(string)
+		if number == TagFinalized || number == TagLatest {
+			number = "0x64"
+		}
+		return fmt.Sprintf(`{"number":"%s","hash":"0x%s%s","transactions":[]}`, number, n.fork, number[2:])
+	case GetTransactionReceipt:
+		return fmt.Sprintf(`{"transactionHash":"%s","blockNumber":"%s"}`, params[0], params[0])
+	}
+	return "null"
+}
+
+func (n *cacheNode) count(method string) int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.calls[method]
+}
+
+// newCacheServer serves node over HTTP.
+func newCacheServer(node *cacheNode) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw json.RawMessage
+		json.NewDecoder(r.Body).Decode(&raw)
+		if raw[0] != '[' {
+			var req RequestBody
+			json.Unmarshal(raw, &req)
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, node.result(req))
+			return
+		}
+		var reqs []RequestBody
+		json.Unmarshal(raw, &reqs)
+		fmt.Fprint(w, "[")
+		for i, req := range reqs {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, node.result(req))
+		}
+		fmt.Fprint(w, "]")
+	}))
+}
+
+func TestCache(t *testing.T) {
+	node := &cacheNode{calls: make(map[string]int), fork: "a", chainID: "0x1"}
+	server := newCacheServer(node)
+	defer server.Close()
+
+	cache := NewCache(CacheConfig{MaxBytes: 1 << 20, FinalityDepth: 64})
+	client := NewETHClient(server.URL, WithInterceptors(cache.Interceptor()))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		var chainID string
+		if err := client.call(ctx, &chainID, GetChainID); err != nil || chainID != "0x1" {
+			t.Fatalf("unexpected chain id %s: %v", chainID, err)
+		}
+		// Head 0x100 makes 0xc0 final.
+		if _, err := client.BlockNumber(ctx); err != nil {
+			t.Fatal(err.Error())
+		}
+		for _, number := range []int{100, 250} {
+			if block, err := client.BlockByNumber(ctx, number); err != nil || block.Hash != fmt.Sprintf("0xa%x", number) {
+				t.Fatalf("unexpected block %+v: %v", block, err)
+			}
+		}
+		for _, hash := range []string{"0x10", "0x200"} {
+			if _, err := client.TransactionReceipt(ctx, hash); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+	}
+	expected := map[string]int{GetChainID: 2, GetBlockbusterMethod: 2, GetBlockByNumber: 3, GetTransactionReceipt: 3}
+	for method, calls := range expected {
+		if node.count(method) != calls {
+			t.Errorf("expected %d calls of %s, got %d", calls, method, node.count(method))
+		}
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Entries != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// Only the missing blocks of a batch are requested.
+	blocks, err := client.BlocksByNumber(ctx, 99, 101)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for i, block := range blocks {
+		if block.Hash != fmt.Sprintf("0xa%x", 99+i) {
+			t.Errorf("unexpected block %d %+v", 99+i, block)
+		}
+	}
+	if calls := node.count(GetBlockByNumber); calls != 5 {
+		t.Errorf("expected 2 more block calls, got %d", calls-3)
+	}
+
+	// A final block with another hash drops the blocks from its height.
+	node.fork = "b"
+	if _, err := client.BlockAt(ctx, FinalizedBlock); err != nil {
+		t.Fatal(err.Error())
+	}
+	if block, err := client.BlockByNumber(ctx, 99); err != nil || block.Hash != "0xa63" {
+		t.Errorf("expected block 99 to stay cached, got %+v: %v", block, err)
+	}
+	if block, err := client.BlockByNumber(ctx, 100); err != nil || block.Hash != "0xb64" {
+		t.Errorf("expected reorganized block 100, got %+v: %v", block, err)
+	}
+
+	cache.Invalidate(0)
+	if stats := cache.Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("expected no entry to be left, got %+v", stats)
+	}
+}
+
+func TestCacheChainSwitch(t *testing.T) {
+	node := &cacheNode{calls: make(map[string]int), fork: "a", chainID: "0x1"}
+	server := newCacheServer(node)
+	defer server.Close()
+	cache := NewCache(DefaultCacheConfig())
+	client := NewETHClient(server.URL, WithInterceptors(cache.Interceptor()))
+	ctx := context.Background()
+
+	if err := client.VerifyChain(ctx, 1); err != nil {
+		t.Fatal(err.Error())
+	}
+	// The endpoint switches to a testnet behind the cached client.
+	node.lock.Lock()
+	node.chainID = "0x5"
+	node.lock.Unlock()
+	if err := client.VerifyChain(ctx, 1); !errors.Is(err, ErrChainMismatch) {
+		t.Errorf("expected ErrChainMismatch, got %v", err)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	cache := NewCache(CacheConfig{MaxBytes: 70})
+	cache.SetFinalized(10)
+	for i := 0; i < 4; i++ {
+		call := RequestBody{Method: GetBalance, Params: []interface{}{"0x1", toBlockNumArg(i)}}
+		cache.store(call, json.RawMessage(`"0x1"`))
+	}
+	// Every entry takes 32 bytes.
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Evictions != 2 || stats.Bytes != 64 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	key, _ := cacheKey(RequestBody{Method: GetBalance, Params: []interface{}{"0x1", "0x3"}})
+	if _, ok := cache.get(key); !ok {
+		t.Error("expected the most recent entry to be cached")
+	}
+}
+
+func TestCacheDefaultFinality(t *testing.T) {
+	cache := NewCache(DefaultCacheConfig())
+	block := func(number string) RequestBody {
+		return RequestBody{Method: GetBlockByNumber, Params: []interface{}{number, false}}
+	}
+
+	// The head number tells nothing about finality.
+	cache.store(RequestBody{Method: GetBlockbusterMethod, Params: []interface{}{}}, json.RawMessage(`"0x100"`))
+	cache.store(block("0x10"), json.RawMessage(`{"number":"0x10","hash":"0xa10"}`))
+	if entries := cache.Stats().Entries; entries != 0 {
+		t.Errorf("expected no block below the head to be cached, got %d entries", entries)
+	}
+
+	cache.store(block(TagFinalized), json.RawMessage(`{"number":"0x64","hash":"0xa64"}`))
+	cache.store(block("0x10"), json.RawMessage(`{"number":"0x10","hash":"0xa10"}`))
+	cache.store(block("0x65"), json.RawMessage(`{"number":"0x65","hash":"0xa65"}`))
+	key, _ := cacheKey(block("0x10"))
+	if _, ok := cache.get(key); !ok || cache.Stats().Entries != 1 {
+		t.Errorf("expected only the block below the finalized one to be cached, got %+v", cache.Stats())
+	}
+}
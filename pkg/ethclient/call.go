@@ -0,0 +1,103 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+const (
+	EthCall = "eth_call"
+)
+
+// CallMsg is a message call executed by eth_call without creating a
+// transaction. Empty fields are left for the node to fill.
+type CallMsg struct {
+	From     string
+	To       string
+	Gas      uint64
+	GasPrice *big.Int
+	Value    *big.Int
+	Data     []byte
+}
+
+func (m CallMsg) arg() map[string]interface{} {
+	arg := map[string]interface{}{"to": m.To}
+	if m.From != "" {
+		arg["from"] = m.From
+	}
+	if m.Gas != 0 {
+		arg["gas"] = fmt.Sprintf("0x%x", m.Gas)
+	}
+	if m.GasPrice != nil {
+		arg["gasPrice"] = toHexBig(m.GasPrice)
+	}
+	if m.Value != nil {
+		arg["value"] = toHexBig(m.Value)
+	}
+	if len(m.Data) > 0 {
+		arg["input"] = toHexBytes(m.Data)
+		// Older nodes only read data.
+		arg["data"] = arg["input"]
+	}
+	return arg
+}
+
+// OverrideAccount replaces parts of an account state for the duration of
+// a call. Nil fields are left untouched. State replaces the whole storage
+// while StateDiff only the given slots, both are keyed by 0x-prefixed
+// slot and hold 0x-prefixed 32 bytes values.
+type OverrideAccount struct {
+	Nonce     *uint64
+	Code      []byte
+	Balance   *big.Int
+	State     map[string]string
+	StateDiff map[string]string
+}
+
+// StateOverride maps account addresses to the overrides applied to them.
+type StateOverride map[string]OverrideAccount
+
+// MarshalJSON encodes the override in the format expected by eth_call.
+func (o OverrideAccount) MarshalJSON() ([]byte, error) {
+	override := make(map[string]interface{})
+	if o.Nonce != nil {
+		override["nonce"] = fmt.Sprintf("0x%x", *o.Nonce)
+	}
+	if o.Code != nil {
+		override["code"] = toHexBytes(o.Code)
+	}
+	if o.Balance != nil {
+		override["balance"] = toHexBig(o.Balance)
+	}
+	if o.State != nil {
+		override["state"] = o.State
+	}
+	if o.StateDiff != nil {
+		override["stateDiff"] = o.StateDiff
+	}
+	return json.Marshal(override)
+}
+
+// Call executes the message call at the given block and returns its
+// return data. overrides may be nil. A reverted call returns an error
+// matching ErrExecutionReverted, with the revert data in the Data field of
+// the RPCError.
+func (c *Client) Call(ctx context.Context, msg CallMsg, block BlockRef, overrides StateOverride) ([]byte, error) {
+	args := []interface{}{msg.arg(), block.arg()}
+	if len(overrides) > 0 {
+		args = append(args, overrides)
+	}
+
+	var result string
+	if err := c.call(ctx, &result, EthCall, args...); err != nil {
+		return nil, err
+	}
+
+	data, err := parseHexBytes(result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing call result: %v", err)
+	}
+	return data, nil
+}
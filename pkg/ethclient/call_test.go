@@ -0,0 +1,91 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	const (
+		token = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+		owner = "0x00000000219ab540356cbb839cbe05303d7705fa"
+	)
+
+	var lastParams []json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.Unmarshal(body, &req)
+		lastParams = req.Params
+
+		var msg map[string]string
+		json.Unmarshal(req.Params[0], &msg)
+		switch {
+		case req.Method != EthCall || msg["to"] != token:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32602,"message":"invalid params"}}`, req.ID)
+		case strings.HasPrefix(msg["input"], "0x70a08231"):
+			// balanceOf
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x00000000000000000000000000000000000000000000000000000000000f4240"}`, req.ID)
+		case strings.HasPrefix(msg["input"], "0x95d89b41"):
+			// symbol
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000045553445400000000000000000000000000000000000000000000000000000000"}`, req.ID)
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":3,"message":"execution reverted: not supported","data":"0x08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000d6e6f7420737570706f7274656400000000000000000000000000000000000000"}}`, req.ID)
+		}
+	}))
+	defer server.Close()
+
+	client := NewETHClient(server.URL)
+	ctx := context.Background()
+
+	balance, err := client.TokenBalance(ctx, token, owner, BlockNumberRef(16))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if balance.Cmp(big.NewInt(1000000)) != 0 {
+		t.Errorf("unexpected balance %s", balance)
+	}
+	if len(lastParams) != 2 || string(lastParams[1]) != `"0x10"` {
+		t.Errorf("unexpected params %s", lastParams)
+	}
+
+	symbol, err := client.TokenSymbol(ctx, token, LatestBlock)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if symbol != "USDT" {
+		t.Errorf("unexpected symbol %q", symbol)
+	}
+
+	// State overrides are sent as the third parameter.
+	nonce := uint64(1)
+	_, err = client.Call(ctx, CallMsg{To: token, Data: []byte{0x70, 0xa0, 0x82, 0x31}}, LatestBlock, StateOverride{
+		owner: {Nonce: &nonce, Balance: big.NewInt(255), StateDiff: map[string]string{"0x0": "0x01"}},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(lastParams) != 3 || string(lastParams[2]) != `{"`+owner+`":{"balance":"0xff","nonce":"0x1","stateDiff":{"0x0":"0x01"}}}` {
+		t.Errorf("unexpected overrides %s", lastParams)
+	}
+
+	_, err = client.TokenDecimals(ctx, token, LatestBlock)
+	if !errors.Is(err, ErrExecutionReverted) {
+		t.Fatalf("expected ErrExecutionReverted, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("revert reason missing from %q", err)
+	}
+}
@@ -0,0 +1,284 @@
+package ethclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrCassetteMismatch is returned by replay clients for requests missing
+// from their cassette.
+var ErrCassetteMismatch = errors.New("request not found in cassette")
+
+// ReplayMode selects how replayed requests are matched to the cassette.
+type ReplayMode int
+
+const (
+	// ReplayStrict expects the recorded requests, in the recorded order
+	// and batches, ids aside.
+	ReplayStrict ReplayMode = iota
+	// ReplayLenient answers every request, or element of a batch, with a
+	// recorded response to the same method and params, in any order.
+	// Responses are replayed in the recorded order, the last one being
+	// repeated once they all were.
+	ReplayLenient
+)
+
+// Interaction is a request and its response as sent over the wire.
+type Interaction struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Cassette holds the interactions recorded by a client, to be replayed
+// later without network, e.g. in tests.
+type Cassette struct {
+	lock         sync.Mutex
+	Interactions []Interaction `json:"interactions"`
+}
+
+func NewCassette() *Cassette {
+	return &Cassette{}
+}
+
+// LoadCassette reads a cassette saved with Save.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cassette := &Cassette{}
+	if err := json.Unmarshal(data, cassette); err != nil {
+		return nil, fmt.Errorf("error decoding cassette %s: %v", path, err)
+	}
+	return cassette, nil
+}
+
+// Save writes the cassette to path as indented JSON.
+func (c *Cassette) Save(path string) error {
+	c.lock.Lock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func (c *Cassette) record(request, response []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.Interactions = append(c.Interactions, Interaction{
+		Request:  append(json.RawMessage(nil), request...),
+		Response: append(json.RawMessage(nil), response...),
+	})
+}
+
+// WithRecorder records the requests of the client, and their responses,
+// in cassette. Requests failing before a response is received, and
+// subscriptions, are not recorded. Requests answered by interceptors are
+// not recorded either.
+func WithRecorder(cassette *Cassette) Option {
+	return func(c *Client) {
+		c.recorder = cassette
+	}
+}
+
+// NewReplayClient returns a client answering requests from the cassette
+// instead of an endpoint. Requests missing from the cassette fail with
+// ErrCassetteMismatch.
+func NewReplayClient(cassette *Cassette, mode ReplayMode, opts ...Option) (*Client, error) {
+	t, err := newReplayTransport(cassette, mode)
+	if err != nil {
+		return nil, err
+	}
+	c := newClient(opts...)
+	c.transport = t
+	return c, nil
+}
+
+// wireCall is a request as matched by replay transports.
+type wireCall struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// key returns the method and params of the call, the params in canonical
+// form so that formatting does not matter.
+func (w wireCall) key() string {
+	var params interface{}
+	if err := json.Unmarshal(w.Params, &params); err != nil {
+		return w.Method + " " + string(w.Params)
+	}
+	canonical, _ := json.Marshal(params)
+	return w.Method + " " + string(canonical)
+}
+
+// wireResponse is a response as replayed, the id rewritten to the id of
+// the request being answered.
+type wireResponse map[string]json.RawMessage
+
+// decodeWire decodes a single or batch message.
+func decodeWire(data []byte, v interface{}) (batch bool, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return true, json.Unmarshal(data, v)
+	}
+	var single json.RawMessage
+	if err := json.Unmarshal(data, &single); err != nil {
+		return false, err
+	}
+	return false, json.Unmarshal(append(append([]byte{'['}, single...), ']'), v)
+}
+
+// replayedInteraction is a decoded interaction of the cassette.
+type replayedInteraction struct {
+	calls     []wireCall
+	responses []wireResponse
+	batch     bool
+}
+
+// replayTransport serves the interactions of a cassette.
+type replayTransport struct {
+	mode ReplayMode
+
+	lock         sync.Mutex
+	interactions []replayedInteraction
+	next         int
+	// byCall holds the recorded responses of each method and params,
+	// used is the number replayed so far.
+	byCall map[string][]wireResponse
+	used   map[string]int
+}
+
+func newReplayTransport(cassette *Cassette, mode ReplayMode) (*replayTransport, error) {
+	t := &replayTransport{
+		mode:   mode,
+		byCall: make(map[string][]wireResponse),
+		used:   make(map[string]int),
+	}
+
+	cassette.lock.Lock()
+	defer cassette.lock.Unlock()
+	for i, interaction := range cassette.Interactions {
+		var decoded replayedInteraction
+		batch, err := decodeWire(interaction.Request, &decoded.calls)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding request of interaction %d: %v", i, err)
+		}
+		if _, err := decodeWire(interaction.Response, &decoded.responses); err != nil {
+			return nil, fmt.Errorf("error decoding response of interaction %d: %v", i, err)
+		}
+		decoded.batch = batch
+		t.interactions = append(t.interactions, decoded)
+
+		responses := make(map[string]wireResponse, len(decoded.responses))
+		for _, resp := range decoded.responses {
+			responses[string(resp["id"])] = resp
+		}
+		if !batch && len(decoded.responses) == 1 {
+			// Errors about the request itself come back without its id.
+			responses[string(decoded.calls[0].ID)] = decoded.responses[0]
+		}
+		for _, call := range decoded.calls {
+			if resp, ok := responses[string(call.ID)]; ok {
+				t.byCall[call.key()] = append(t.byCall[call.key()], resp)
+			}
+		}
+	}
+	return t, nil
+}
+
+func (t *replayTransport) roundTrip(ctx context.Context, body []byte) ([]byte, error) {
+	var calls []wireCall
+	batch, err := decodeWire(body, &calls)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding request: %v", err)
+	}
+
+	t.lock.Lock()
+	var responses []wireResponse
+	if t.mode == ReplayStrict {
+		responses, err = t.matchNext(calls, batch)
+	} else {
+		responses, err = t.matchCalls(calls)
+	}
+	t.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if !batch {
+		return json.Marshal(responses[0])
+	}
+	return json.Marshal(responses)
+}
+
+// matchNext answers the request with the next interaction of the
+// cassette, which must have the same calls.
+func (t *replayTransport) matchNext(calls []wireCall, batch bool) ([]wireResponse, error) {
+	if t.next >= len(t.interactions) {
+		return nil, fmt.Errorf("%w: %s after the last interaction", ErrCassetteMismatch, calls[0].key())
+	}
+	interaction := t.interactions[t.next]
+	if interaction.batch != batch || len(interaction.calls) != len(calls) {
+		return nil, fmt.Errorf("%w: interaction %d has %d calls, got %d", ErrCassetteMismatch, t.next, len(interaction.calls), len(calls))
+	}
+	ids := make(map[string]json.RawMessage, len(calls))
+	for i, call := range calls {
+		recorded := interaction.calls[i]
+		if recorded.key() != call.key() {
+			return nil, fmt.Errorf("%w: interaction %d expects %s, got %s", ErrCassetteMismatch, t.next, recorded.key(), call.key())
+		}
+		ids[string(recorded.ID)] = call.ID
+	}
+	t.next++
+
+	if !batch {
+		return []wireResponse{interaction.responses[0].withID(calls[0].ID)}, nil
+	}
+	responses := make([]wireResponse, 0, len(interaction.responses))
+	for _, resp := range interaction.responses {
+		if id, ok := ids[string(resp["id"])]; ok {
+			responses = append(responses, resp.withID(id))
+		}
+	}
+	return responses, nil
+}
+
+// matchCalls answers every call with a response recorded for the same
+// method and params.
+func (t *replayTransport) matchCalls(calls []wireCall) ([]wireResponse, error) {
+	responses := make([]wireResponse, len(calls))
+	for i, call := range calls {
+		key := call.key()
+		recorded := t.byCall[key]
+		if len(recorded) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrCassetteMismatch, key)
+		}
+		n := t.used[key]
+		if n >= len(recorded) {
+			n = len(recorded) - 1
+		} else {
+			t.used[key]++
+		}
+		responses[i] = recorded[n].withID(call.ID)
+	}
+	return responses, nil
+}
+
+func (r wireResponse) withID(id json.RawMessage) wireResponse {
+	resp := make(wireResponse, len(r))
+	for k, v := range r {
+		resp[k] = v
+	}
+	resp["id"] = id
+	return resp
+}
+
+func (t *replayTransport) close() {}
@@ -0,0 +1,124 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestCassette(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw json.RawMessage
+		json.NewDecoder(r.Body).Decode(&raw)
+		if raw[0] == '[' {
+			var reqs []RequestBody
+			json.Unmarshal(raw, &reqs)
+			fmt.Fprintf(w, `[{"jsonrpc":"2.0","id":%d,"result":"0x2"},{"jsonrpc":"2.0","id":%d,"result":"0x1"}]`, reqs[1].ID, reqs[0].ID)
+			return
+		}
+		var req RequestBody
+		json.Unmarshal(raw, &req)
+		switch req.Method {
+		case GetBalance:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"%s"}`, req.ID, req.Params.([]interface{})[0])
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"method not found"}}`, req.ID)
+		}
+	}))
+	defer server.Close()
+
+	// Record a call, a batch and an error, then save and load the cassette.
+	recording := NewCassette()
+	client := NewETHClient(server.URL, WithRecorder(recording))
+	ctx := context.Background()
+	if _, err := client.BalanceAt(ctx, "0x3", LatestBlock); err != nil {
+		t.Fatal(err.Error())
+	}
+	var first, second string
+	batch := []BatchElem{
+		{Method: GetBalance, Args: []interface{}{"0x1", "latest"}, Result: &first},
+		{Method: GetBalance, Args: []interface{}{"0x2", "latest"}, Result: &second},
+	}
+	if err := client.BatchCall(ctx, batch); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := client.BlockNumber(ctx); !isMethodNotFound(err) {
+		t.Fatalf("expected method not found, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := recording.Save(path); err != nil {
+		t.Fatal(err.Error())
+	}
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(cassette.Interactions) != 3 {
+		t.Fatalf("expected 3 interactions, got %d", len(cassette.Interactions))
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		replay, err := NewReplayClient(cassette, ReplayStrict)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		// Out of order requests are rejected.
+		if _, err := replay.BlockNumber(ctx); !errors.Is(err, ErrCassetteMismatch) {
+			t.Fatalf("expected cassette mismatch, got %v", err)
+		}
+		if balance, err := replay.BalanceAt(ctx, "0x3", LatestBlock); err != nil || balance.Int64() != 3 {
+			t.Fatalf("unexpected balance %v: %v", balance, err)
+		}
+		first, second = "", ""
+		if err := replay.BatchCall(ctx, batch); err != nil {
+			t.Fatal(err.Error())
+		}
+		if first != "0x1" || second != "0x2" {
+			t.Errorf("unexpected batch results %s, %s", first, second)
+		}
+		if _, err := replay.BlockNumber(ctx); !isMethodNotFound(err) {
+			t.Errorf("expected method not found, got %v", err)
+		}
+		if _, err := replay.BlockNumber(ctx); !errors.Is(err, ErrCassetteMismatch) {
+			t.Errorf("expected cassette mismatch past the end, got %v", err)
+		}
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		replay, err := NewReplayClient(cassette, ReplayLenient)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		// Batch elements are answered on their own, and responses repeat.
+		for i := 0; i < 2; i++ {
+			if balance, err := replay.BalanceAt(ctx, "0x2", LatestBlock); err != nil || balance.Int64() != 2 {
+				t.Fatalf("unexpected balance %v: %v", balance, err)
+			}
+		}
+		var third string
+		batch := []BatchElem{
+			{Method: GetBalance, Args: []interface{}{"0x3", "latest"}, Result: &third},
+			{Method: GetBalance, Args: []interface{}{"0x1", "latest"}, Result: &first},
+		}
+		if err := replay.BatchCall(ctx, batch); err != nil {
+			t.Fatal(err.Error())
+		}
+		if third != "0x3" || first != "0x1" {
+			t.Errorf("unexpected batch results %s, %s", third, first)
+		}
+		if _, err := replay.BalanceAt(ctx, "0x4", LatestBlock); !errors.Is(err, ErrCassetteMismatch) {
+			t.Errorf("expected cassette mismatch, got %v", err)
+		}
+	})
+}
+
+func isMethodNotFound(err error) bool {
+	var rpcErr *RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == CodeMethodNotFound
+}
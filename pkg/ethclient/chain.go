@@ -0,0 +1,124 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	GetChainID = "eth_chainId"
+	NetVersion = "net_version"
+
+	NetPeerCount = "net_peerCount"
+	NetListening = "net_listening"
+)
+
+// ErrChainMismatch is returned when an endpoint serves another chain than
+// the expected one.
+var ErrChainMismatch = errors.New("chain mismatch")
+
+// ChainID returns the EIP-155 chain id of the node.
+func (c *Client) ChainID(ctx context.Context) (uint64, error) {
+	var result string
+	if err := c.call(ctx, &result, GetChainID); err != nil {
+		return 0, err
+	}
+
+	chainID, err := parseHexUint64(result)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing chain id: %v", err)
+	}
+	return chainID, nil
+}
+
+// NetworkID returns the network id of the node, which is the chain id on
+// most networks.
+func (c *Client) NetworkID(ctx context.Context) (uint64, error) {
+	var result string
+	if err := c.call(ctx, &result, NetVersion); err != nil {
+		return 0, err
+	}
+
+	networkID, err := strconv.ParseUint(result, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing network id: %v", err)
+	}
+	return networkID, nil
+}
+
+// PeerCount returns the number of peers connected to the node.
+func (c *Client) PeerCount(ctx context.Context) (uint64, error) {
+	var result string
+	if err := c.call(ctx, &result, NetPeerCount); err != nil {
+		return 0, err
+	}
+
+	peers, err := parseHexUint64(result)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing peer count: %v", err)
+	}
+	return peers, nil
+}
+
+// Listening reports whether the node accepts connections from peers.
+func (c *Client) Listening(ctx context.Context) (bool, error) {
+	var listening bool
+	if err := c.call(ctx, &listening, NetListening); err != nil {
+		return false, err
+	}
+	return listening, nil
+}
+
+// VerifyChain checks that the node serves the chain with the given id,
+// with eth_chainId or net_version for nodes predating it. It returns
+// ErrChainMismatch otherwise.
+//
+// A client created with NewETHPoolClient checks every endpoint, ejects
+// the ones serving another chain and keeps checking them on every probe,
+// so an endpoint switching networks is ejected too. It only fails if no
+// endpoint serves the chain.
+func (c *Client) VerifyChain(ctx context.Context, chainID uint64) error {
+	if p, ok := c.transport.(*poolTransport); ok {
+		return p.verifyChain(ctx, chainID)
+	}
+
+	served, err := chainIDOf(func(result interface{}, method string) error {
+		return c.call(ctx, result, method)
+	})
+	if err != nil {
+		return err
+	}
+	if served != chainID {
+		return fmt.Errorf("%w: node serves chain %d, expected %d", ErrChainMismatch, served, chainID)
+	}
+	return nil
+}
+
+// chainIDOf returns the chain id of the node behind call, falling back to
+// net_version if eth_chainId is not supported.
+func chainIDOf(call func(result interface{}, method string) error) (uint64, error) {
+	var result string
+	err := call(&result, GetChainID)
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == CodeMethodNotFound {
+		if err := call(&result, NetVersion); err != nil {
+			return 0, err
+		}
+		networkID, err := strconv.ParseUint(result, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("error parsing network id: %v", err)
+		}
+		return networkID, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	chainID, err := parseHexUint64(result)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing chain id: %v", err)
+	}
+	return chainID, nil
+}
@@ -0,0 +1,102 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newChainServer serves the head, the chain id and the network id of the
+// given chain, which may change over time, and counts the block number
+// requests. Without chainIDMethod it answers eth_chainId with method not
+// found, like old nodes.
+func newChainServer(chainID *uint64, chainIDMethod bool, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RequestBody
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case req.Method == GetChainID && chainIDMethod:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x%x"}`, req.ID, atomic.LoadUint64(chainID))
+		case req.Method == NetVersion:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"%d"}`, req.ID, atomic.LoadUint64(chainID))
+		case req.Method == GetBlockbusterMethod:
+			atomic.AddInt32(calls, 1)
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x64"}`, req.ID)
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"method not found"}}`, req.ID)
+		}
+	}))
+}
+
+func TestVerifyChain(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Client", func(t *testing.T) {
+		var chainID uint64 = 5
+		var calls int32
+		server := newChainServer(&chainID, false, &calls)
+		defer server.Close()
+		client := NewETHClient(server.URL)
+
+		if err := client.VerifyChain(ctx, 5); err != nil {
+			t.Errorf("expected net_version to be used, got %v", err)
+		}
+		if err := client.VerifyChain(ctx, 1); !errors.Is(err, ErrChainMismatch) {
+			t.Errorf("expected chain mismatch, got %v", err)
+		}
+	})
+
+	t.Run("Pool", func(t *testing.T) {
+		var mainnet, testnet uint64 = 1, 5
+		var mainnetCalls, testnetCalls int32
+		first := newChainServer(&testnet, true, &testnetCalls)
+		defer first.Close()
+		second := newChainServer(&mainnet, true, &mainnetCalls)
+		defer second.Close()
+
+		client, err := NewETHPoolClient([]string{first.URL, second.URL}, PoolConfig{EjectAfter: 2})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer client.Close()
+		if err := client.VerifyChain(ctx, 1); err != nil {
+			t.Fatal(err.Error())
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := client.BlockNumber(ctx); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+		if atomic.LoadInt32(&testnetCalls) != 0 || atomic.LoadInt32(&mainnetCalls) != 3 {
+			t.Errorf("expected all traffic on mainnet, got %d testnet and %d mainnet calls", testnetCalls, mainnetCalls)
+		}
+		if stats := client.EndpointStats(); stats[0].ChainID != 5 || stats[1].ChainID != 1 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+
+		// The mainnet endpoint switches networks, probes find out.
+		atomic.StoreUint64(&mainnet, 5)
+		client.transport.(*poolTransport).probe(ctx)
+		if _, err := client.BlockNumber(ctx); !errors.Is(err, ErrChainMismatch) {
+			t.Errorf("expected chain mismatch, got %v", err)
+		}
+		if err := client.VerifyChain(ctx, 1); !errors.Is(err, ErrChainMismatch) {
+			t.Errorf("expected chain mismatch, got %v", err)
+		}
+
+		atomic.StoreUint64(&testnet, 1)
+		client.transport.(*poolTransport).probe(ctx)
+		atomic.StoreInt32(&testnetCalls, 0)
+		if _, err := client.BlockNumber(ctx); err != nil {
+			t.Errorf("expected the endpoint back on mainnet to be used, got %v", err)
+		}
+		if atomic.LoadInt32(&testnetCalls) != 1 {
+			t.Error("expected traffic on the endpoint back on mainnet")
+		}
+	})
+}
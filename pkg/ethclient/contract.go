@@ -0,0 +1,65 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/352174109/trustwallet-homework/pkg/abi"
+)
+
+// CallMethod calls a view function of the contract at the given block and
+// decodes its return values. The reason of a reverted call is decoded into
+// the returned error when the contract provides one.
+func (c *Client) CallMethod(ctx context.Context, contract string, method abi.Method, block BlockRef, args ...interface{}) ([]interface{}, error) {
+	data, err := method.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.Call(ctx, CallMsg{To: contract, Data: data}, block, nil)
+	if err != nil {
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) && errors.Is(err, ErrExecutionReverted) {
+			if reason, reasonErr := abi.UnpackRevert(rpcErr.RevertData()); reasonErr == nil {
+				return nil, fmt.Errorf("%s reverted: %w: %s", method.Name, ErrExecutionReverted, reason)
+			}
+		}
+		return nil, err
+	}
+
+	values, err := method.Unpack(result)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s result: %w", method.Name, err)
+	}
+	return values, nil
+}
+
+// TokenBalance returns the ERC-20 balance of owner in the smallest unit of
+// the token.
+func (c *Client) TokenBalance(ctx context.Context, token, owner string, block BlockRef) (*big.Int, error) {
+	values, err := c.CallMethod(ctx, token, abi.ERC20BalanceOf, block, owner)
+	if err != nil {
+		return nil, err
+	}
+	return values[0].(*big.Int), nil
+}
+
+// TokenDecimals returns the number of decimals of an ERC-20 token.
+func (c *Client) TokenDecimals(ctx context.Context, token string, block BlockRef) (int, error) {
+	values, err := c.CallMethod(ctx, token, abi.ERC20Decimals, block)
+	if err != nil {
+		return 0, err
+	}
+	return int(values[0].(*big.Int).Int64()), nil
+}
+
+// TokenSymbol returns the symbol of an ERC-20 token.
+func (c *Client) TokenSymbol(ctx context.Context, token string, block BlockRef) (string, error) {
+	values, err := c.CallMethod(ctx, token, abi.ERC20Symbol, block)
+	if err != nil {
+		return "", err
+	}
+	return values[0].(string), nil
+}
@@ -0,0 +1,109 @@
+package ethclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrBlockNotFound is returned when the node answers a block query
+	// with a null result, e.g. the block is not mined or not yet synced.
+	ErrBlockNotFound = errors.New("block not found")
+
+	// ErrRateLimited is returned when the provider throttles the client,
+	// either with HTTP 429 or with a JSON-RPC limit error.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrExecutionReverted is returned when a call reverts, the revert data
+	// is in the Data field of the RPCError.
+	ErrExecutionReverted = errors.New("execution reverted")
+
+	// errTransport marks requests that failed before a response was received.
+	errTransport = errors.New("error making request")
+)
+
+// Standard and widely used provider JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeServerError    = -32000
+	CodeLimitExceeded  = -32005
+
+	// CodeExecutionReverted is used by geth and most providers for
+	// reverted calls.
+	CodeExecutionReverted = 3
+)
+
+// RPCError is the error object returned in a JSON-RPC response.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// Is reports whether the error matches one of the sentinel errors of
+// this package.
+func (e *RPCError) Is(target error) bool {
+	switch target {
+	case ErrExecutionReverted:
+		return e.Code == CodeExecutionReverted || strings.HasPrefix(e.Message, "execution reverted")
+	case ErrQueryTooLarge:
+		return isQueryTooLargeMessage(e.Message)
+	case ErrFilterNotFound:
+		return isFilterNotFoundMessage(e.Message)
+	case ErrRateLimited:
+		// Some providers also use the limit code for queries too large.
+		if isQueryTooLargeMessage(e.Message) {
+			return false
+		}
+		if e.Code == CodeLimitExceeded || e.Code == http.StatusTooManyRequests {
+			return true
+		}
+		msg := strings.ToLower(e.Message)
+		return strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+	}
+	return false
+}
+
+// RevertData returns the data of a reverted call, nil if there is none.
+func (e *RPCError) RevertData() []byte {
+	var data string
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		return nil
+	}
+	b, err := parseHexBytes(data)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// HTTPError is returned when the endpoint answers with a non 200 status.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+	// RetryAfter is the delay requested by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("error response status code: %v", e.StatusCode)
+}
+
+// Is reports whether the error matches one of the sentinel errors of
+// this package.
+func (e *HTTPError) Is(target error) bool {
+	return target == ErrRateLimited && e.StatusCode == http.StatusTooManyRequests
+}
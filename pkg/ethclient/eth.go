@@ -0,0 +1,110 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	GetTransactionByBlockHashAndIndex   = "eth_getTransactionByBlockHashAndIndex"
+	GetTransactionByBlockNumberAndIndex = "eth_getTransactionByBlockNumberAndIndex"
+	GetUncleCountByBlockHash            = "eth_getUncleCountByBlockHash"
+	GetUncleCountByBlockNumber          = "eth_getUncleCountByBlockNumber"
+	Syncing                             = "eth_syncing"
+)
+
+// SyncProgress is the progress of a node catching up with the chain.
+type SyncProgress struct {
+	StartingBlock uint64
+	CurrentBlock  uint64
+	HighestBlock  uint64
+}
+
+// TransactionInBlock returns the transaction at the given index of the
+// referenced block. It returns ErrTransactionNotFound if the node knows no
+// such block or transaction.
+func (c *Client) TransactionInBlock(ctx context.Context, ref BlockRef, index int) (*ETHTransaction, error) {
+	var tx *ETHTransaction
+	var err error
+	if hash, ok := ref.Hash(); ok {
+		err = c.call(ctx, &tx, GetTransactionByBlockHashAndIndex, hash, fmt.Sprintf("0x%x", index))
+	} else {
+		err = c.call(ctx, &tx, GetTransactionByBlockNumberAndIndex, ref.arg(), fmt.Sprintf("0x%x", index))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, ErrTransactionNotFound
+	}
+	return tx, nil
+}
+
+// UncleCount returns the number of uncles of the referenced block, 0 for
+// every block since the merge. It returns ErrBlockNotFound if the node
+// does not know the block.
+func (c *Client) UncleCount(ctx context.Context, ref BlockRef) (int, error) {
+	var result *string
+	var err error
+	if hash, ok := ref.Hash(); ok {
+		err = c.call(ctx, &result, GetUncleCountByBlockHash, hash)
+	} else {
+		err = c.call(ctx, &result, GetUncleCountByBlockNumber, ref.arg())
+	}
+	if err != nil {
+		return 0, err
+	}
+	if result == nil {
+		return 0, ErrBlockNotFound
+	}
+
+	count, err := parseHexInt(*result)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing uncle count: %v", err)
+	}
+	return count, nil
+}
+
+// SyncProgress returns the progress of the node catching up with the
+// chain, nil once it is in sync. Blocks past CurrentBlock are not served
+// yet by a syncing node.
+func (c *Client) SyncProgress(ctx context.Context) (*SyncProgress, error) {
+	var result json.RawMessage
+	if err := c.call(ctx, &result, Syncing); err != nil {
+		return nil, err
+	}
+
+	var syncing bool
+	if err := json.Unmarshal(result, &syncing); err == nil {
+		if syncing {
+			return nil, fmt.Errorf("error parsing sync progress: %s", result)
+		}
+		return nil, nil
+	}
+
+	var raw struct {
+		StartingBlock string `json:"startingBlock"`
+		CurrentBlock  string `json:"currentBlock"`
+		HighestBlock  string `json:"highestBlock"`
+	}
+	if err := json.Unmarshal(result, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing sync progress: %v", err)
+	}
+	progress := &SyncProgress{}
+	for _, field := range []struct {
+		value  string
+		parsed *uint64
+	}{
+		{raw.StartingBlock, &progress.StartingBlock},
+		{raw.CurrentBlock, &progress.CurrentBlock},
+		{raw.HighestBlock, &progress.HighestBlock},
+	} {
+		n, err := parseHexUint64(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing sync progress: %v", err)
+		}
+		*field.parsed = n
+	}
+	return progress, nil
+}
@@ -0,0 +1,118 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// namespaceNode answers the requests with the results registered by method
+// and JSON encoded params, and with a method not found error otherwise.
+func namespaceNode(results map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		params, _ := json.Marshal(req.Params)
+		result, ok := results[req.Method+string(params)]
+		if !ok {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"the method %s does not exist/is not available"}}`, req.ID, req.Method)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, result)
+	}))
+}
+
+func TestGenericCall(t *testing.T) {
+	server := namespaceNode(map[string]string{
+		`web3_clientVersion[]`:                        `"Geth/v1.14.8"`,
+		`eth_getBlockTransactionCountByNumber["0x1"]`: `null`,
+	})
+	defer server.Close()
+	client := NewETHClient(server.URL)
+	ctx := context.Background()
+
+	var version string
+	if err := client.CallRPC(ctx, &version, "web3_clientVersion"); err != nil || version != "Geth/v1.14.8" {
+		t.Errorf("unexpected version %q: %v", version, err)
+	}
+	count := new(string)
+	if err := client.CallRPC(ctx, &count, GetBlockTransactionCountByNumber, "0x1"); err != nil || count != nil {
+		t.Errorf("expected null result to clear the pointer, got %v: %v", count, err)
+	}
+
+	var rpcErr *RPCError
+	err := client.CallRPC(ctx, &version, "eth_mining")
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+		t.Errorf("expected method not found, got %v", err)
+	}
+}
+
+func TestNamespaceMethods(t *testing.T) {
+	const hash = "0x88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6"
+	server := namespaceNode(map[string]string{
+		`eth_getTransactionByBlockHashAndIndex["` + hash + `","0x1"]`: `{"hash":"0x1","transactionIndex":"0x1"}`,
+		`eth_getTransactionByBlockNumberAndIndex["latest","0x2"]`:     `null`,
+		`eth_getUncleCountByBlockNumber["0x64"]`:                      `"0x2"`,
+		`eth_getUncleCountByBlockHash["` + hash + `"]`:                `null`,
+		`eth_blobBaseFee[]`: `"0x1"`,
+		`net_peerCount[]`:   `"0x19"`,
+		`net_listening[]`:   `true`,
+	})
+	defer server.Close()
+	client := NewETHClient(server.URL)
+	ctx := context.Background()
+
+	tx, err := client.TransactionInBlock(ctx, BlockHashRef(hash, false), 1)
+	if err != nil || tx.Hash != "0x1" {
+		t.Errorf("unexpected transaction %+v: %v", tx, err)
+	}
+	if _, err := client.TransactionInBlock(ctx, LatestBlock, 2); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("expected ErrTransactionNotFound, got %v", err)
+	}
+
+	if count, err := client.UncleCount(ctx, BlockNumberRef(100)); err != nil || count != 2 {
+		t.Errorf("unexpected uncle count %d: %v", count, err)
+	}
+	if _, err := client.UncleCount(ctx, BlockHashRef(hash, false)); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	if fee, err := client.BlobBaseFee(ctx); err != nil || fee.Int64() != 1 {
+		t.Errorf("unexpected blob base fee %v: %v", fee, err)
+	}
+	if peers, err := client.PeerCount(ctx); err != nil || peers != 25 {
+		t.Errorf("unexpected peer count %d: %v", peers, err)
+	}
+	if listening, err := client.Listening(ctx); err != nil || !listening {
+		t.Errorf("expected node to listen: %v", err)
+	}
+}
+
+func TestSyncProgress(t *testing.T) {
+	for _, test := range []struct {
+		result   string
+		expected *SyncProgress
+		fails    bool
+	}{
+		{`false`, nil, false},
+		{`{"startingBlock":"0x64","currentBlock":"0x3e8","highestBlock":"0x2710","pulledStates":"0x0"}`, &SyncProgress{StartingBlock: 100, CurrentBlock: 1000, HighestBlock: 10000}, false},
+		{`{"startingBlock":"0x64"}`, nil, true},
+		{`true`, nil, true},
+	} {
+		server := namespaceNode(map[string]string{`eth_syncing[]`: test.result})
+		progress, err := NewETHClient(server.URL).SyncProgress(context.Background())
+		server.Close()
+		if (err != nil) != test.fails || !reflect.DeepEqual(progress, test.expected) {
+			t.Errorf("%s: unexpected progress %+v: %v", test.result, progress, err)
+		}
+	}
+}
@@ -4,81 +4,423 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	ApiVersion           = "2.0"
 	GetBlockbusterMethod = "eth_blockNumber"
 	GetBlockByNumber     = "eth_getBlockByNumber"
+	GetBlockByHash       = "eth_getBlockByHash"
+
+	GetUncleByBlockHashAndIndex      = "eth_getUncleByBlockHashAndIndex"
+	GetBlockTransactionCountByNumber = "eth_getBlockTransactionCountByNumber"
+	GetBlockTransactionCountByHash   = "eth_getBlockTransactionCountByHash"
 )
 
 type Client struct {
-	endpoint string
+	transport    transport
+	retry        RetryPolicy
+	timeout      time.Duration
+	http         httpConfig
+	interceptors []Interceptor
+	limiter      *rateLimiter
+	recorder     *Cassette
+
+	// idCounter is used to give every request in a batch a unique
+	// JSON-RPC id so responses can be matched back to their request.
+	idCounter uint32
+}
+
+// NewETHClient returns a client for the given endpoint. ws:// and wss://
+// endpoints use a WebSocket connection and ipc:///path/to/geth.ipc
+// endpoints the Unix socket of a local node, both supporting
+// subscriptions. Any other endpoint is reached over HTTP.
+func NewETHClient(endpoint string, opts ...Option) *Client {
+	c := newClient(opts...)
+	c.transport = newEndpointTransport(endpoint, c.http)
+	return c
 }
 
-func NewETHClient(endpoint string) *Client {
-	return &Client{
-		endpoint: endpoint,
+// newEndpointTransport returns the transport matching the scheme of the
+// endpoint.
+func newEndpointTransport(endpoint string, config httpConfig) transport {
+	switch {
+	case strings.HasPrefix(endpoint, "ws://"), strings.HasPrefix(endpoint, "wss://"):
+		return newWSTransport(endpoint, config)
+	case strings.HasPrefix(endpoint, ipcScheme):
+		return newIPCTransport(strings.TrimPrefix(endpoint, ipcScheme))
 	}
+	return newHTTPTransport(endpoint, config)
+}
+
+// newClient returns a client configured with opts, the caller sets its
+// transport.
+func newClient(opts ...Option) *Client {
+	c := &Client{
+		timeout: defaultRequestTimeout,
+		http: httpConfig{
+			client:          &http.Client{},
+			headers:         make(http.Header),
+			maxResponseSize: defaultMaxResponseSize,
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Close releases the resources held by the client transport.
+func (c *Client) Close() {
+	c.transport.close()
 }
 
 // BlockNumber returns the current block number. It will call
 // the eth_blockNumber method of the JSON-RPC API in the given endpoint.
 func (c *Client) BlockNumber(ctx context.Context) (int, error) {
-	body, err := json.Marshal(makeRequestBody(GetBlockbusterMethod, []interface{}{}))
+	var result string
+	if err := c.call(ctx, &result, GetBlockbusterMethod); err != nil {
+		return 0, err
+	}
+
+	blockNumber, err := parseHexInt(result)
 	if err != nil {
-		return 0, fmt.Errorf("error marshaling json: %v", err)
+		return 0, fmt.Errorf("error parsing response body: %v", err)
+	}
+
+	return blockNumber, nil
+}
+
+// BlockNumberAt returns the number of the referenced block, e.g. the
+// current safe or finalized block.
+func (c *Client) BlockNumberAt(ctx context.Context, ref BlockRef) (int, error) {
+	if number, ok := ref.Number(); ok {
+		return number, nil
+	}
+	if ref.Tag() == TagLatest {
+		return c.BlockNumber(ctx)
+	}
+
+	var block *struct {
+		Number string `json:"number"`
+	}
+	if err := c.callBlock(ctx, &block, ref, false); err != nil {
+		return 0, err
+	}
+	if block == nil {
+		return 0, ErrBlockNotFound
 	}
 
-	r, err := http.Post(c.endpoint, "application/json", bytes.NewReader(body))
+	blockNumber, err := parseHexInt(block.Number)
 	if err != nil {
-		return 0, fmt.Errorf("error making request: %v", err)
+		return 0, fmt.Errorf("error parsing block number: %v", err)
 	}
-	if r.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("error response status code: %v", r.StatusCode)
+	return blockNumber, nil
+}
+
+// BlockByNumber returns the block with the given number including its
+// transactions. It returns ErrBlockNotFound if the node does not know the
+// block yet.
+func (c *Client) BlockByNumber(ctx context.Context, blockNumber int) (*ETHBlock, error) {
+	return c.BlockAt(ctx, BlockNumberRef(blockNumber))
+}
+
+// BlockAt returns the referenced block including its transactions. It
+// returns ErrBlockNotFound if the node does not know the block.
+func (c *Client) BlockAt(ctx context.Context, ref BlockRef) (*ETHBlock, error) {
+	var block *ETHBlock
+	if err := c.callBlock(ctx, &block, ref, true); err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, ErrBlockNotFound
 	}
+	return block, nil
+}
+
+// BlockByHash returns the block with the given hash including its
+// transactions, canonical or not. It returns ErrBlockNotFound if the node
+// does not know the block.
+func (c *Client) BlockByHash(ctx context.Context, hash string) (*ETHBlock, error) {
+	return c.BlockAt(ctx, BlockHashRef(hash, false))
+}
 
-	getblockNumRsp := &GetBlockNumberResp{}
-	if err := json.NewDecoder(r.Body).Decode(getblockNumRsp); err != nil {
-		return 0, fmt.Errorf("error decoding response body: %v", err)
+// HeaderAt returns the header of the referenced block, without downloading
+// its transactions. It returns ErrBlockNotFound if the node does not know
+// the block.
+func (c *Client) HeaderAt(ctx context.Context, ref BlockRef) (*Header, error) {
+	var header *Header
+	if err := c.callBlock(ctx, &header, ref, false); err != nil {
+		return nil, err
 	}
+	if header == nil {
+		return nil, ErrBlockNotFound
+	}
+	return header, nil
+}
+
+// HeaderByNumber returns the header of the block with the given number.
+func (c *Client) HeaderByNumber(ctx context.Context, blockNumber int) (*Header, error) {
+	return c.HeaderAt(ctx, BlockNumberRef(blockNumber))
+}
+
+// HeaderByHash returns the header of the block with the given hash,
+// canonical or not.
+func (c *Client) HeaderByHash(ctx context.Context, hash string) (*Header, error) {
+	return c.HeaderAt(ctx, BlockHashRef(hash, false))
+}
+
+// UncleByBlockHashAndIndex returns the header of the uncle at the given
+// index of the block with the given hash. It returns ErrBlockNotFound if
+// the node knows no such block or uncle, as for every block since the
+// merge.
+func (c *Client) UncleByBlockHashAndIndex(ctx context.Context, hash string, index int) (*Header, error) {
+	var header *Header
+	if err := c.call(ctx, &header, GetUncleByBlockHashAndIndex, hash, fmt.Sprintf("0x%x", index)); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, ErrBlockNotFound
+	}
+	return header, nil
+}
+
+// BlockTransactionCountByNumber returns the number of transactions of the
+// block with the given number.
+func (c *Client) BlockTransactionCountByNumber(ctx context.Context, blockNumber int) (int, error) {
+	return c.BlockTransactionCount(ctx, BlockNumberRef(blockNumber))
+}
 
-	blockNumber, err := strconv.ParseInt(getblockNumRsp.Result[2:], 16, 64)
+// BlockTransactionCount returns the number of transactions of the
+// referenced block. It returns ErrBlockNotFound if the node does not know
+// the block.
+func (c *Client) BlockTransactionCount(ctx context.Context, ref BlockRef) (int, error) {
+	var result *string
+	var err error
+	if hash, ok := ref.Hash(); ok {
+		err = c.call(ctx, &result, GetBlockTransactionCountByHash, hash)
+	} else {
+		err = c.call(ctx, &result, GetBlockTransactionCountByNumber, ref.arg())
+	}
 	if err != nil {
-		return 0, fmt.Errorf("error parsing response body: %v", err)
+		return 0, err
+	}
+	if result == nil {
+		return 0, ErrBlockNotFound
 	}
 
-	return int(blockNumber), nil
+	count, err := parseHexInt(*result)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing transaction count: %v", err)
+	}
+	return count, nil
 }
 
-func (c *Client) BlockByNumber(ctx context.Context, blockNumber int) (*ETHBlock, error) {
-	body, err := json.Marshal(makeRequestBody(GetBlockByNumber, []interface{}{fmt.Sprintf("0x%x", blockNumber), true}))
+// callBlock queries the referenced block by number or by hash, block
+// methods do not accept EIP-1898 objects.
+func (c *Client) callBlock(ctx context.Context, result interface{}, ref BlockRef, fullTx bool) error {
+	if hash, ok := ref.Hash(); ok {
+		return c.call(ctx, result, GetBlockByHash, hash, fullTx)
+	}
+	return c.call(ctx, result, GetBlockByNumber, ref.arg(), fullTx)
+}
+
+// BlocksByNumber returns the blocks in the range [from, to] using a single
+// batch request. Blocks are returned in ascending order. If an element of
+// the batch fails, the blocks preceding it are returned together with the
+// error of the first failed element.
+func (c *Client) BlocksByNumber(ctx context.Context, from, to int) ([]*ETHBlock, error) {
+	return c.blocksByNumber(ctx, from, to, nil)
+}
+
+// blocksByNumber queries the blocks in the range [from, to], decoding only
+// the transactions accepted by filter unless it is nil.
+func (c *Client) blocksByNumber(ctx context.Context, from, to int, filter TransactionFilter) ([]*ETHBlock, error) {
+	if to < from {
+		return nil, fmt.Errorf("invalid block range [%d, %d]", from, to)
+	}
+
+	blocks := make([]*ETHBlock, to-from+1)
+	batch := make([]BatchElem, len(blocks))
+	for i := range batch {
+		batch[i] = BatchElem{
+			Method: GetBlockByNumber,
+			Args:   []interface{}{toBlockNumArg(from + i), true},
+			Result: &blocks[i],
+		}
+		if filter != nil {
+			batch[i].Result = &filteredBlock{filter: filter, block: &blocks[i]}
+		}
+	}
+	if err := c.BatchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	for i, elem := range batch {
+		if elem.Error == nil && blocks[i] == nil {
+			elem.Error = ErrBlockNotFound
+		}
+		if elem.Error != nil {
+			return blocks[:i], fmt.Errorf("error querying block %d: %w", from+i, elem.Error)
+		}
+	}
+	return blocks, nil
+}
+
+// BatchElem is a single request in a batch call. Result must be a pointer
+// the response is decoded into; Error is set when that element fails, even
+// if the rest of the batch succeeds.
+type BatchElem struct {
+	Method string
+	Args   []interface{}
+	Result interface{}
+	Error  error
+}
+
+// BatchCall sends all given requests as a single JSON-RPC batch. The
+// returned error only reports failures of the batch as a whole, like a
+// transport error; failures of individual requests are set on the Error
+// field of the corresponding element.
+func (c *Client) BatchCall(ctx context.Context, b []BatchElem) error {
+	if len(b) == 0 {
+		return nil
+	}
+
+	reqs := make([]RequestBody, len(b))
+	pending := make(map[int]int, len(b))
+	for i, elem := range b {
+		args := elem.Args
+		if args == nil {
+			args = []interface{}{}
+		}
+		reqs[i] = c.makeRequestBody(elem.Method, args)
+		pending[reqs[i].ID] = i
+	}
+
+	var resps []*ResponseBody
+	err := c.retry.retry(ctx, func() error {
+		resp, err := c.post(ctx, &Request{Calls: reqs, Batch: true})
+		if err != nil {
+			return err
+		}
+
+		// A server rejecting the batch as a whole answers with a single
+		// response object instead of an array.
+		raw := bytes.TrimSpace(resp.Body)
+		if len(raw) > 0 && raw[0] == '{' {
+			resp := &ResponseBody{}
+			if err := json.Unmarshal(raw, resp); err != nil {
+				return fmt.Errorf("error decoding response body: %v", err)
+			}
+			if resp.Error != nil {
+				return resp.Error
+			}
+			return fmt.Errorf("unexpected non-batch response")
+		}
+
+		if err := json.Unmarshal(raw, &resps); err != nil {
+			return fmt.Errorf("error decoding response body: %v", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling json: %v", err)
+		return err
 	}
 
-	resp, err := http.Post(c.endpoint, "application/json", bytes.NewReader(body))
+	for _, resp := range resps {
+		i, ok := pending[resp.ID]
+		if !ok {
+			continue
+		}
+		delete(pending, resp.ID)
+
+		elem := &b[i]
+		switch {
+		case resp.Error != nil:
+			elem.Error = resp.Error
+		case elem.Result != nil:
+			if err := json.Unmarshal(resp.Result, elem.Result); err != nil {
+				elem.Error = fmt.Errorf("error decoding result: %v", err)
+			}
+		}
+	}
+	for id, i := range pending {
+		b[i].Error = fmt.Errorf("missing response for request id %d", id)
+	}
+	return nil
+}
+
+// CallRPC performs a JSON-RPC request of any method through the retries,
+// interceptors and transport of the client, and decodes its result into
+// result, which must be a pointer. A null result sets pointers, slices and
+// maps to nil. Failures reported by the node are returned as *RPCError.
+//
+//	var peers string
+//	err := client.CallRPC(ctx, &peers, "net_peerCount")
+func (c *Client) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	return c.call(ctx, result, method, params...)
+}
+
+// call performs a single JSON-RPC request and decodes its result into
+// result, which must be a pointer.
+func (c *Client) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if args == nil {
+		args = []interface{}{}
+	}
+	req := c.makeRequestBody(method, args)
+
+	var resp *Response
+	err := c.retry.retry(ctx, func() error {
+		var err error
+		resp, err = c.post(ctx, &Request{Calls: []RequestBody{req}})
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
+		return err
+	}
+	if err := json.Unmarshal(resp.decoded.Result, result); err != nil {
+		return fmt.Errorf("error decoding result: %v", err)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error response status code: %v", resp.StatusCode)
+	return nil
+}
+
+// post sends the request through the interceptors and the transport of
+// the client.
+func (c *Client) post(ctx context.Context, req *Request) (*Response, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	resp, err := c.chain()(ctx, req)
+	if err == nil && resp == nil {
+		return nil, errors.New("interceptor returned no response")
+	}
+	if err != nil || req.Batch || resp.decoded != nil {
+		return resp, err
 	}
 
-	ethBlockRsp := &GetBlockByNumberResp{}
-	if err := json.NewDecoder(resp.Body).Decode(ethBlockRsp); err != nil {
+	// The response was made up by an interceptor.
+	resp.decoded = &ResponseBody{}
+	if err := json.Unmarshal(resp.Body, resp.decoded); err != nil {
 		return nil, fmt.Errorf("error decoding response body: %v", err)
 	}
-	return ethBlockRsp.Result, nil
+	if resp.decoded.Error != nil {
+		return resp, resp.decoded.Error
+	}
+	return resp, nil
 }
 
-func makeRequestBody(method string, params interface{}) RequestBody {
+func (c *Client) makeRequestBody(method string, params interface{}) RequestBody {
 	return RequestBody{
 		Jsonrpc: ApiVersion,
-		ID:      1,
+		ID:      int(atomic.AddUint32(&c.idCounter, 1)),
 		Method:  method,
 		Params:  params,
 	}
@@ -3,9 +3,13 @@ package ethclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -51,3 +55,385 @@ func TestClient(t *testing.T) {
 		}
 	})
 }
+
+func TestBatchCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []RequestBody
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Answer in reverse order to check responses are matched by id.
+		resps := make([]string, 0, len(reqs))
+		for i := len(reqs) - 1; i >= 0; i-- {
+			req := reqs[i]
+			number := req.Params.([]interface{})[0].(string)
+			if number == "0x11" {
+				resps = append(resps, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-32000,"message":"header not found"}}`, req.ID))
+				continue
+			}
+			resps = append(resps, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":{"number":"%s","hash":"0x1","transactions":[]}}`, req.ID, number))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(resps, ","))
+	}))
+	defer server.Close()
+
+	client := NewETHClient(server.URL)
+
+	t.Run("BatchCall", func(t *testing.T) {
+		var first, second, third *ETHBlock
+		batch := []BatchElem{
+			{Method: GetBlockByNumber, Args: []interface{}{"0xf", true}, Result: &first},
+			{Method: GetBlockByNumber, Args: []interface{}{"0x10", true}, Result: &second},
+			{Method: GetBlockByNumber, Args: []interface{}{"0x11", true}, Result: &third},
+		}
+		if err := client.BatchCall(context.Background(), batch); err != nil {
+			t.Fatal(err.Error())
+		}
+		if batch[0].Error != nil || first.Number != "0xf" {
+			t.Errorf("unexpected first element: %v %+v", batch[0].Error, first)
+		}
+		if batch[1].Error != nil || second.Number != "0x10" {
+			t.Errorf("unexpected second element: %v %+v", batch[1].Error, second)
+		}
+		if batch[2].Error == nil {
+			t.Error("expected error for third element")
+		}
+	})
+
+	t.Run("BlocksByNumber", func(t *testing.T) {
+		blocks, err := client.BlocksByNumber(context.Background(), 14, 18)
+		if err == nil {
+			t.Error("expected error for block 0x11")
+		}
+		if len(blocks) != 3 {
+			t.Fatalf("expected 3 blocks before the failed one, got %d", len(blocks))
+		}
+		for i, block := range blocks {
+			if block.Number != fmt.Sprintf("0x%x", 14+i) {
+				t.Errorf("unexpected block number %s at %d", block.Number, i)
+			}
+		}
+	})
+}
+
+func TestClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RequestBody
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch req.Method {
+		case GetBlockbusterMethod:
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"daily request count exceeded","data":{"see":"https://example.com"}}}`)
+		case GetBlockByNumber:
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":null}`)
+		default:
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	client := NewETHClient(server.URL)
+
+	t.Run("RPCError", func(t *testing.T) {
+		_, err := client.BlockNumber(context.Background())
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			t.Fatalf("expected RPCError, got %v", err)
+		}
+		if rpcErr.Code != CodeLimitExceeded || string(rpcErr.Data) != `{"see":"https://example.com"}` {
+			t.Errorf("unexpected rpc error %+v", rpcErr)
+		}
+		if !errors.Is(err, ErrRateLimited) {
+			t.Error("expected ErrRateLimited")
+		}
+	})
+
+	t.Run("NullBlock", func(t *testing.T) {
+		block, err := client.BlockByNumber(context.Background(), 16)
+		if !errors.Is(err, ErrBlockNotFound) || block != nil {
+			t.Errorf("expected ErrBlockNotFound, got %v", err)
+		}
+	})
+
+	t.Run("HTTPStatus", func(t *testing.T) {
+		var result string
+		err := client.call(context.Background(), &result, "eth_chainId")
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("expected ErrRateLimited, got %v", err)
+		}
+	})
+}
+
+func TestReceipts(t *testing.T) {
+	const receipt = `{"transactionHash":"%s","blockNumber":"0x10","status":"0x1","gasUsed":"0x5208","cumulativeGasUsed":"0x5208","effectiveGasPrice":"0x3b9aca00","contractAddress":null,"logs":[{"address":"0xa","topics":["0xb"],"data":"0x","logIndex":"0x0"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if body[0] == '[' {
+			var reqs []RequestBody
+			json.Unmarshal(body, &reqs)
+			resps := make([]string, len(reqs))
+			for i, req := range reqs {
+				hash := req.Params.([]interface{})[0].(string)
+				if hash == "0xpending" {
+					resps[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":null}`, req.ID)
+					continue
+				}
+				resps[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":`+receipt+`}`, req.ID, hash)
+			}
+			fmt.Fprintf(w, "[%s]", strings.Join(resps, ","))
+			return
+		}
+
+		var req RequestBody
+		json.Unmarshal(body, &req)
+		switch req.Method {
+		case GetTransactionReceipt:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":`+receipt+`}`, req.ID, "0x1")
+		case GetBlockReceipts:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"the method eth_getBlockReceipts does not exist/is not available"}}`, req.ID)
+		}
+	}))
+	defer server.Close()
+
+	client := NewETHClient(server.URL)
+
+	t.Run("TransactionReceipt", func(t *testing.T) {
+		receipt, err := client.TransactionReceipt(context.Background(), "0x1")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if receipt.Status != "0x1" || receipt.GasUsed != "0x5208" || len(receipt.Logs) != 1 || receipt.Logs[0].Topics[0] != "0xb" {
+			t.Errorf("unexpected receipt %+v", receipt)
+		}
+	})
+
+	t.Run("TransactionReceipts", func(t *testing.T) {
+		receipts, err := client.TransactionReceipts(context.Background(), []string{"0x1", "0x2"})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if receipts[0].TransactionHash != "0x1" || receipts[1].TransactionHash != "0x2" {
+			t.Errorf("unexpected receipts %+v %+v", receipts[0], receipts[1])
+		}
+
+		if _, err := client.TransactionReceipts(context.Background(), []string{"0x1", "0xpending"}); !errors.Is(err, ErrReceiptNotFound) {
+			t.Errorf("expected ErrReceiptNotFound, got %v", err)
+		}
+	})
+
+	t.Run("BlockReceiptsUnsupported", func(t *testing.T) {
+		_, err := client.BlockReceipts(context.Background(), BlockNumberRef(16))
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+			t.Errorf("expected method not found, got %v", err)
+		}
+	})
+}
+
+func TestAccountState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RequestBody
+		json.NewDecoder(r.Body).Decode(&req)
+
+		params := req.Params.([]interface{})
+		if block := params[len(params)-1]; block != "0x10" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32602,"message":"unexpected block %v"}}`, req.ID, block)
+			return
+		}
+		switch req.Method {
+		case GetBalance:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x14d1120d7b160000"}`, req.ID)
+		case GetTransactionCount:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x2a"}`, req.ID)
+		case GetCode:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x6080"}`, req.ID)
+		case GetStorageAt:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x0000000000000000000000000000000000000000000000000000000000000001"}`, req.ID)
+		}
+	}))
+	defer server.Close()
+
+	client := NewETHClient(server.URL)
+	ctx := context.Background()
+	block, err := ParseBlockRef("16")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	balance, err := client.BalanceAt(ctx, "0xa", block)
+	if err != nil || balance.String() != "1500000000000000000" {
+		t.Errorf("unexpected balance %v: %v", balance, err)
+	}
+	nonce, err := client.NonceAt(ctx, "0xa", block)
+	if err != nil || nonce != 42 {
+		t.Errorf("unexpected nonce %d: %v", nonce, err)
+	}
+	code, err := client.CodeAt(ctx, "0xa", block)
+	if err != nil || len(code) != 2 || code[0] != 0x60 {
+		t.Errorf("unexpected code %x: %v", code, err)
+	}
+	value, err := client.StorageAt(ctx, "0xa", "0x0", block)
+	if err != nil || len(value) != 32 || value[31] != 1 {
+		t.Errorf("unexpected storage %x: %v", value, err)
+	}
+	if _, err := client.BalanceAt(ctx, "0xa", LatestBlock); err == nil {
+		t.Error("expected error for latest block")
+	}
+}
+
+func TestBlockRef(t *testing.T) {
+	hash := "0x88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6"
+	tests := []struct {
+		input string
+		arg   interface{}
+	}{
+		{"", "latest"},
+		{"safe", "safe"},
+		{"finalized", "finalized"},
+		{"0", "0x0"},
+		{"0x10", "0x10"},
+		{hash, map[string]interface{}{"blockHash": hash, "requireCanonical": false}},
+	}
+	for _, test := range tests {
+		ref, err := ParseBlockRef(test.input)
+		if err != nil {
+			t.Errorf("error parsing %q: %v", test.input, err)
+			continue
+		}
+		if arg := ref.arg(); !reflect.DeepEqual(arg, test.arg) {
+			t.Errorf("unexpected arg for %q: %v", test.input, arg)
+		}
+	}
+	if _, err := ParseBlockRef("-1"); err == nil {
+		t.Error("expected error for negative block")
+	}
+	if BlockNumberRef(0) == LatestBlock || (BlockRef{}).Tag() != TagLatest {
+		t.Error("block 0 must differ from the latest block")
+	}
+}
+
+func TestBlockAt(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RequestBody
+		json.NewDecoder(r.Body).Decode(&req)
+		methods = append(methods, req.Method)
+
+		params := req.Params.([]interface{})
+		switch params[0] {
+		case "finalized", "0x88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{"number":"0x64","hash":"0x1","transactions":[]}}`, req.ID)
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":null}`, req.ID)
+		}
+	}))
+	defer server.Close()
+
+	client := NewETHClient(server.URL)
+	ctx := context.Background()
+
+	finalized, err := client.BlockNumberAt(ctx, FinalizedBlock)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if finalized != 100 {
+		t.Errorf("expected finalized block 100, got %d", finalized)
+	}
+
+	block, err := client.BlockAt(ctx, BlockHashRef("0x88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6", true))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if block.Number != "0x64" {
+		t.Errorf("unexpected block %s", block.Number)
+	}
+	if _, err := client.BlockAt(ctx, SafeBlock); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	expected := []string{GetBlockByNumber, GetBlockByHash, GetBlockByNumber}
+	if !reflect.DeepEqual(methods, expected) {
+		t.Errorf("expected methods %v, got %v", expected, methods)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	const hash = "0x88e96d4537bea4d9c05d12549907b32561d3bf31f45aae734cdc119f13406cb6"
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		params, _ := json.Marshal(req.Params)
+		requests = append(requests, req.Method+string(params))
+
+		result := "null"
+		switch req.Method {
+		case GetBlockByHash, GetBlockByNumber:
+			if string(req.Params[0]) != `"0x65"` {
+				result = `{"number":"0x64","hash":"` + hash + `","parentHash":"0x2","transactions":[]}`
+			}
+		case GetUncleByBlockHashAndIndex:
+			if string(req.Params[1]) == `"0x1"` {
+				result = `{"number":"0x63","hash":"0x4","parentHash":"0x5"}`
+			}
+		case GetBlockTransactionCountByNumber, GetBlockTransactionCountByHash:
+			result = `"0x1"`
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, result)
+	}))
+	defer server.Close()
+
+	client := NewETHClient(server.URL)
+	ctx := context.Background()
+
+	header, err := client.HeaderByHash(ctx, hash)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if header.Number != "0x64" || header.ParentHash != "0x2" {
+		t.Errorf("unexpected header %+v", header)
+	}
+	if _, err := client.HeaderByNumber(ctx, 101); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+	if block, err := client.BlockByHash(ctx, hash); err != nil || block.Hash != hash {
+		t.Errorf("unexpected block %+v: %v", block, err)
+	}
+
+	uncle, err := client.UncleByBlockHashAndIndex(ctx, hash, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if uncle.Hash != "0x4" {
+		t.Errorf("unexpected uncle %+v", uncle)
+	}
+	if _, err := client.UncleByBlockHashAndIndex(ctx, hash, 0); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	if count, err := client.BlockTransactionCountByNumber(ctx, 100); err != nil || count != 1 {
+		t.Errorf("unexpected transaction count %d: %v", count, err)
+	}
+	if count, err := client.BlockTransactionCount(ctx, BlockHashRef(hash, true)); err != nil || count != 1 {
+		t.Errorf("unexpected transaction count %d: %v", count, err)
+	}
+
+	expected := []string{
+		GetBlockByHash + `["` + hash + `",false]`,
+		GetBlockByNumber + `["0x65",false]`,
+		GetBlockByHash + `["` + hash + `",true]`,
+		GetUncleByBlockHashAndIndex + `["` + hash + `","0x1"]`,
+		GetUncleByBlockHashAndIndex + `["` + hash + `","0x0"]`,
+		GetBlockTransactionCountByNumber + `["0x64"]`,
+		GetBlockTransactionCountByHash + `["` + hash + `"]`,
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+}
@@ -0,0 +1,210 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const GetLogs = "eth_getLogs"
+
+// ErrQueryTooLarge is returned when the provider rejects a query because
+// its block range or its result is too large.
+var ErrQueryTooLarge = errors.New("query too large")
+
+// queryTooLargeMessages are fragments of the messages providers use to
+// reject eth_getLogs queries spanning too many blocks or results.
+var queryTooLargeMessages = []string{
+	"query returned more than",
+	"block range",
+	"range too large",
+	"range is too large",
+	"too many blocks",
+	"too many results",
+	"response size exceeded",
+	"response size should not",
+	"exceed maximum block range",
+	"limit the query to",
+}
+
+func isQueryTooLargeMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, fragment := range queryTooLargeMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterQuery selects logs by block range or block hash, emitting
+// addresses and topics. Build it with NewFilter.
+type FilterQuery struct {
+	// FromBlock and ToBlock bound the range, nil means the latest block.
+	FromBlock *int
+	ToBlock   *int
+	// BlockHash restricts the query to a single block, it excludes a range.
+	BlockHash string
+	// Addresses matches logs emitted by any of the contracts, all if empty.
+	Addresses []string
+	// Topics matches logs by position, each position matching any of its
+	// values. An empty position matches any topic.
+	Topics [][]string
+}
+
+// NewFilter returns an empty query, matching every log of the latest block.
+func NewFilter() *FilterQuery {
+	return &FilterQuery{}
+}
+
+// Range sets the inclusive block range of the query.
+func (q *FilterQuery) Range(from, to int) *FilterQuery {
+	q.FromBlock, q.ToBlock = &from, &to
+	return q
+}
+
+// From sets the first block of the query.
+func (q *FilterQuery) From(from int) *FilterQuery {
+	q.FromBlock = &from
+	return q
+}
+
+// To sets the last block of the query.
+func (q *FilterQuery) To(to int) *FilterQuery {
+	q.ToBlock = &to
+	return q
+}
+
+// AtBlockHash restricts the query to the block with the given hash.
+func (q *FilterQuery) AtBlockHash(hash string) *FilterQuery {
+	q.BlockHash = hash
+	return q
+}
+
+// Address adds contracts the logs may be emitted by.
+func (q *FilterQuery) Address(addresses ...string) *FilterQuery {
+	q.Addresses = append(q.Addresses, addresses...)
+	return q
+}
+
+// Topic adds values the topic at the given position may match.
+func (q *FilterQuery) Topic(position int, values ...string) *FilterQuery {
+	for len(q.Topics) <= position {
+		q.Topics = append(q.Topics, nil)
+	}
+	q.Topics[position] = append(q.Topics[position], values...)
+	return q
+}
+
+// toArg returns the eth_getLogs filter object.
+func (q *FilterQuery) toArg() (map[string]interface{}, error) {
+	arg := make(map[string]interface{})
+	if q.BlockHash != "" {
+		if q.FromBlock != nil || q.ToBlock != nil {
+			return nil, errors.New("filter with block hash can not have a block range")
+		}
+		arg["blockHash"] = q.BlockHash
+	} else {
+		if q.FromBlock != nil {
+			arg["fromBlock"] = toBlockNumArg(*q.FromBlock)
+		}
+		if q.ToBlock != nil {
+			arg["toBlock"] = toBlockNumArg(*q.ToBlock)
+		}
+	}
+	if len(q.Addresses) > 0 {
+		arg["address"] = q.Addresses
+	}
+
+	// Trailing wildcards are dropped, inner ones are sent as null.
+	topics := q.Topics
+	for len(topics) > 0 && len(topics[len(topics)-1]) == 0 {
+		topics = topics[:len(topics)-1]
+	}
+	if len(topics) > 0 {
+		positions := make([]interface{}, len(topics))
+		for i, values := range topics {
+			if len(values) > 0 {
+				positions[i] = values
+			}
+		}
+		arg["topics"] = positions
+	}
+	return arg, nil
+}
+
+// FilterLogs returns the logs matching the query. When the provider rejects
+// a block range as too large, the range is split in halves until every
+// part is accepted.
+func (c *Client) FilterLogs(ctx context.Context, q FilterQuery) ([]*Log, error) {
+	logs, err := c.getLogs(ctx, q)
+	if err == nil || !errors.Is(err, ErrQueryTooLarge) || q.BlockHash != "" {
+		return logs, err
+	}
+
+	from, to, err := c.resolveRange(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if from >= to {
+		return nil, fmt.Errorf("error querying logs of block %d: %w", from, ErrQueryTooLarge)
+	}
+	return c.filterLogsSplit(ctx, q, from, to)
+}
+
+func (c *Client) filterLogsSplit(ctx context.Context, q FilterQuery, from, to int) ([]*Log, error) {
+	mid := from + (to-from)/2
+	var logs []*Log
+	for _, part := range [][2]int{{from, mid}, {mid + 1, to}} {
+		partQuery := q
+		partQuery.Range(part[0], part[1])
+
+		partLogs, err := c.getLogs(ctx, partQuery)
+		if errors.Is(err, ErrQueryTooLarge) && part[0] < part[1] {
+			partLogs, err = c.filterLogsSplit(ctx, q, part[0], part[1])
+		}
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, partLogs...)
+	}
+	return logs, nil
+}
+
+// resolveRange returns the numeric block range of the query, looking up
+// the head block for open ends.
+func (c *Client) resolveRange(ctx context.Context, q FilterQuery) (int, int, error) {
+	if q.FromBlock != nil && q.ToBlock != nil {
+		return *q.FromBlock, *q.ToBlock, nil
+	}
+
+	head, err := c.BlockNumber(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	from, to := head, head
+	if q.FromBlock != nil {
+		from = *q.FromBlock
+	}
+	if q.ToBlock != nil {
+		to = *q.ToBlock
+	}
+	return from, to, nil
+}
+
+func (c *Client) getLogs(ctx context.Context, q FilterQuery) ([]*Log, error) {
+	arg, err := q.toArg()
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []*Log
+	if err := c.call(ctx, &logs, GetLogs, arg); err != nil {
+		if errors.Is(err, ErrResponseTooLarge) {
+			return nil, fmt.Errorf("%w: %v", ErrQueryTooLarge, err)
+		}
+		return nil, err
+	}
+	return logs, nil
+}
@@ -0,0 +1,83 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFilterQueryArg(t *testing.T) {
+	q := NewFilter().
+		Range(16, 32).
+		Address("0xa", "0xb").
+		Topic(0, "0xddf252ad").
+		Topic(2, "0x01", "0x02").
+		Topic(3)
+	arg, err := q.toArg()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	raw, _ := json.Marshal(arg)
+	expected := `{"address":["0xa","0xb"],"fromBlock":"0x10","toBlock":"0x20","topics":[["0xddf252ad"],null,["0x01","0x02"]]}`
+	if string(raw) != expected {
+		t.Errorf("unexpected filter %s", raw)
+	}
+
+	if _, err := NewFilter().From(1).AtBlockHash("0x1").toArg(); err == nil {
+		t.Error("expected error for block hash with range")
+	}
+}
+
+func TestFilterLogs(t *testing.T) {
+	const maxRange = 10
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var req struct {
+			ID     int `json:"id"`
+			Params []struct {
+				FromBlock string `json:"fromBlock"`
+				ToBlock   string `json:"toBlock"`
+			} `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		from, _ := parseHexInt(req.Params[0].FromBlock)
+		to, _ := parseHexInt(req.Params[0].ToBlock)
+		if to-from+1 > maxRange {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32005,"message":"query returned more than 10000 results"}}`, req.ID)
+			return
+		}
+
+		// One log per block.
+		logs := make([]string, 0, to-from+1)
+		for n := from; n <= to; n++ {
+			logs = append(logs, fmt.Sprintf(`{"address":"0xa","topics":["0xddf252ad"],"data":"0x","blockNumber":"0x%x","logIndex":"0x0"}`, n))
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":[%s]}`, req.ID, strings.Join(logs, ","))
+	}))
+	defer server.Close()
+
+	client := NewETHClient(server.URL, WithRetryPolicy(testRetryPolicy()))
+	logs, err := client.FilterLogs(context.Background(), *NewFilter().Range(100, 140).Topic(0, "0xddf252ad"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(logs) != 41 {
+		t.Fatalf("expected 41 logs, got %d", len(logs))
+	}
+	for i, log := range logs {
+		if log.BlockNumber != toBlockNumArg(100+i) {
+			t.Errorf("log %d out of order: block %s", i, log.BlockNumber)
+		}
+	}
+	// [100, 140] is split in [100, 120] and [121, 140], both rejected. Only
+	// [100, 110] of their halves is still too large and split once more.
+	if atomic.LoadInt32(&calls) != 9 {
+		t.Errorf("expected 9 calls, got %d", calls)
+	}
+}
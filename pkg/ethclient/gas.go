@@ -0,0 +1,118 @@
+package ethclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+const (
+	GetGasPrice             = "eth_gasPrice"
+	GetMaxPriorityFeePerGas = "eth_maxPriorityFeePerGas"
+	GetFeeHistory           = "eth_feeHistory"
+	GetBlobBaseFee          = "eth_blobBaseFee"
+)
+
+// FeeHistory is the fee market history of a range of blocks, as returned
+// by eth_feeHistory.
+type FeeHistory struct {
+	// OldestBlock is the number of the first block of the range.
+	OldestBlock int
+	// BaseFee is the base fee per gas in wei of every block of the range,
+	// followed by the one of the next block.
+	BaseFee []*big.Int
+	// GasUsedRatio is the fraction of the gas limit used by every block.
+	GasUsedRatio []float64
+	// Reward holds for every block the priority fee per gas in wei paid
+	// at each of the requested percentiles, weighted by gas used.
+	Reward [][]*big.Int
+}
+
+// SuggestGasPrice returns the gas price in wei suggested by the node for
+// legacy transactions.
+func (c *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var result string
+	if err := c.call(ctx, &result, GetGasPrice); err != nil {
+		return nil, err
+	}
+
+	price, err := parseHexBig(result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing gas price: %v", err)
+	}
+	return price, nil
+}
+
+// SuggestGasTipCap returns the priority fee per gas in wei suggested by the
+// node for EIP-1559 transactions.
+func (c *Client) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	var result string
+	if err := c.call(ctx, &result, GetMaxPriorityFeePerGas); err != nil {
+		return nil, err
+	}
+
+	tip, err := parseHexBig(result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing priority fee: %v", err)
+	}
+	return tip, nil
+}
+
+// BlobBaseFee returns the EIP-4844 blob base fee per blob gas in wei of
+// the next block.
+func (c *Client) BlobBaseFee(ctx context.Context) (*big.Int, error) {
+	var result string
+	if err := c.call(ctx, &result, GetBlobBaseFee); err != nil {
+		return nil, err
+	}
+
+	fee, err := parseHexBig(result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing blob base fee: %v", err)
+	}
+	return fee, nil
+}
+
+// FeeHistory returns the fee history of blockCount blocks up to the newest
+// one, with the priority fees paid at the given percentiles of every
+// block, in ascending order between 0 and 100. Nodes may return fewer
+// blocks than requested.
+func (c *Client) FeeHistory(ctx context.Context, blockCount int, newest BlockRef, rewardPercentiles []float64) (*FeeHistory, error) {
+	var result struct {
+		OldestBlock   string     `json:"oldestBlock"`
+		BaseFeePerGas []string   `json:"baseFeePerGas"`
+		GasUsedRatio  []float64  `json:"gasUsedRatio"`
+		Reward        [][]string `json:"reward"`
+	}
+	if rewardPercentiles == nil {
+		rewardPercentiles = []float64{}
+	}
+	if err := c.call(ctx, &result, GetFeeHistory, toBlockNumArg(blockCount), newest.arg(), rewardPercentiles); err != nil {
+		return nil, err
+	}
+
+	oldest, err := parseHexInt(result.OldestBlock)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing oldest block: %v", err)
+	}
+	history := &FeeHistory{
+		OldestBlock:  oldest,
+		BaseFee:      make([]*big.Int, len(result.BaseFeePerGas)),
+		GasUsedRatio: result.GasUsedRatio,
+		Reward:       make([][]*big.Int, len(result.Reward)),
+	}
+	for i, fee := range result.BaseFeePerGas {
+		if history.BaseFee[i], err = parseHexBig(fee); err != nil {
+			return nil, fmt.Errorf("error parsing base fee: %v", err)
+		}
+	}
+	for i, rewards := range result.Reward {
+		history.Reward[i] = make([]*big.Int, len(rewards))
+		for j, reward := range rewards {
+			if history.Reward[i][j], err = parseHexBig(reward); err != nil {
+				return nil, fmt.Errorf("error parsing reward: %v", err)
+			}
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,58 @@
+package ethclient
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// parseHexInt parses a 0x-prefixed hex quantity.
+func parseHexInt(s string) (int, error) {
+	n, err := parseHexUint64(s)
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// parseHexUint64 parses a 0x-prefixed hex quantity.
+func parseHexUint64(s string) (uint64, error) {
+	if len(s) < 3 || s[:2] != "0x" {
+		return 0, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	return strconv.ParseUint(s[2:], 16, 64)
+}
+
+// parseHexBig parses a 0x-prefixed hex quantity of any size.
+func parseHexBig(s string) (*big.Int, error) {
+	if len(s) < 3 || s[:2] != "0x" {
+		return nil, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	n, ok := new(big.Int).SetString(s[2:], 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	return n, nil
+}
+
+// parseHexBytes parses 0x-prefixed hex data.
+func parseHexBytes(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return nil, fmt.Errorf("invalid hex data %q", s)
+	}
+	return hex.DecodeString(s[2:])
+}
+
+func toBlockNumArg(blockNumber int) string {
+	return fmt.Sprintf("0x%x", blockNumber)
+}
+
+func toHexBig(n *big.Int) string {
+	return fmt.Sprintf("0x%x", n)
+}
+
+func toHexBytes(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
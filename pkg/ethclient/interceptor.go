@@ -0,0 +1,283 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/352174109/trustwallet-homework/internal/logs"
+)
+
+// Request is a JSON-RPC request, single or batch, on its way through the
+// interceptors to the transport.
+type Request struct {
+	// Calls holds the request, or every request of a batch. Interceptors
+	// may change it, it is encoded after the last interceptor.
+	Calls []RequestBody
+	Batch bool
+	// Header is sent along with the request by HTTP transports, other
+	// transports ignore it.
+	Header http.Header
+	// Tags label the request for the interceptors, see WithRequestTags.
+	Tags map[string]string
+}
+
+// Methods returns the methods of the request.
+func (r *Request) Methods() []string {
+	methods := make([]string, len(r.Calls))
+	for i, call := range r.Calls {
+		methods[i] = call.Method
+	}
+	return methods
+}
+
+// Response is the raw response to a Request.
+type Response struct {
+	Body []byte
+
+	// decoded is the response of a single request, decoded once by the
+	// innermost handler.
+	decoded *ResponseBody
+}
+
+// Size returns the size of the response in bytes, 0 for a nil response.
+func (r *Response) Size() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.Body)
+}
+
+// Handler sends a request and returns its response. For single requests
+// JSON-RPC errors are returned as an *RPCError along with the response,
+// errors of batch elements are left in the body.
+type Handler func(ctx context.Context, req *Request) (*Response, error)
+
+// Interceptor wraps the handler of the next interceptor, or the transport
+// for the last one. It may inspect or change the request and the
+// response, answer on its own or fail the request. Interceptors see every
+// attempt of retried requests.
+type Interceptor func(next Handler) Handler
+
+// WithInterceptors adds interceptors around every request, the first one
+// being the outermost.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// chain returns the handler running the interceptors of the client before
+// the transport.
+func (c *Client) chain() Handler {
+	handler := c.send
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		handler = c.interceptors[i](handler)
+	}
+	return handler
+}
+
+// send encodes the request and hands it to the transport, once the rate
+// limiter lets it through.
+func (c *Client) send(ctx context.Context, req *Request) (*Response, error) {
+	if c.limiter != nil {
+		release, err := c.limiter.acquire(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	var payload interface{} = req.Calls
+	if !req.Batch {
+		payload = req.Calls[0]
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling json: %v", err)
+	}
+	if len(req.Header) > 0 {
+		ctx = context.WithValue(ctx, requestHeaderKey{}, req.Header)
+	}
+
+	raw, err := c.transport.roundTrip(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.recorder != nil {
+		c.recorder.record(body, raw)
+	}
+
+	resp := &Response{Body: raw}
+	if !req.Batch {
+		resp.decoded = &ResponseBody{}
+		if err := json.Unmarshal(raw, resp.decoded); err != nil {
+			return resp, fmt.Errorf("error decoding response body: %v", err)
+		}
+		if resp.decoded.Error != nil {
+			return resp, resp.decoded.Error
+		}
+	}
+	return resp, nil
+}
+
+// requestHeaderKey is the context key of the headers set by interceptors.
+type requestHeaderKey struct{}
+
+// requestTagsKey is the context key of the tags set by WithRequestTags.
+type requestTagsKey struct{}
+
+// WithRequestTags returns a context labeling the requests made with it,
+// e.g. with the component issuing them. Tags are added to the tags of the
+// parent context and are picked up by TaggingInterceptor.
+func WithRequestTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string)
+	if parent, ok := ctx.Value(requestTagsKey{}).(map[string]string); ok {
+		for k, v := range parent {
+			merged[k] = v
+		}
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, requestTagsKey{}, merged)
+}
+
+// TaggingInterceptor sets the tags of every request from the given static
+// tags and the tags of its context, the latter taking precedence. If
+// header is not empty, the tags are also sent in that header as
+// comma separated key=value pairs, for providers to break usage down.
+func TaggingInterceptor(header string, tags map[string]string) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			if req.Tags == nil {
+				req.Tags = make(map[string]string)
+			}
+			for k, v := range tags {
+				req.Tags[k] = v
+			}
+			if ctxTags, ok := ctx.Value(requestTagsKey{}).(map[string]string); ok {
+				for k, v := range ctxTags {
+					req.Tags[k] = v
+				}
+			}
+
+			if header != "" && len(req.Tags) > 0 {
+				pairs := make([]string, 0, len(req.Tags))
+				for k, v := range req.Tags {
+					pairs = append(pairs, k+"="+v)
+				}
+				sort.Strings(pairs)
+				if req.Header == nil {
+					req.Header = make(http.Header)
+				}
+				req.Header.Set(header, strings.Join(pairs, ","))
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// LoggingInterceptor logs every request at debug level, with its duration,
+// response size and error.
+func LoggingInterceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			start := time.Now()
+			resp, err := next(ctx, req)
+			duration := time.Since(start)
+
+			methods := strings.Join(req.Methods(), ",")
+			if req.Batch {
+				methods = fmt.Sprintf("batch(%d)[%s]", len(req.Calls), methods)
+			}
+			if err != nil {
+				logs.CtxDebug(ctx, "rpc %s tags %v failed after %s: %s", methods, req.Tags, duration, err)
+			} else {
+				logs.CtxDebug(ctx, "rpc %s tags %v took %s, %d bytes", methods, req.Tags, duration, resp.Size())
+			}
+			return resp, err
+		}
+	}
+}
+
+// MethodStats are the counters of a JSON-RPC method.
+type MethodStats struct {
+	Calls  int64
+	Errors int64
+	// Bytes is the size of the responses, a batch counts for each of its
+	// methods.
+	Bytes        int64
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+}
+
+// AverageLatency returns the mean latency of the calls.
+func (s MethodStats) AverageLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Calls)
+}
+
+// Metrics counts calls, errors and latencies per method. Requests of a
+// batch are counted with the latency and the error of the whole batch.
+type Metrics struct {
+	lock    sync.Mutex
+	methods map[string]*MethodStats
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{methods: make(map[string]*MethodStats)}
+}
+
+// Interceptor returns the interceptor updating the metrics.
+func (m *Metrics) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			start := time.Now()
+			resp, err := next(ctx, req)
+			m.observe(req, time.Since(start), resp.Size(), err)
+			return resp, err
+		}
+	}
+}
+
+func (m *Metrics) observe(req *Request, latency time.Duration, size int, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, call := range req.Calls {
+		stats, ok := m.methods[call.Method]
+		if !ok {
+			stats = &MethodStats{}
+			m.methods[call.Method] = stats
+		}
+		stats.Calls++
+		if err != nil {
+			stats.Errors++
+		}
+		stats.Bytes += int64(size)
+		stats.TotalLatency += latency
+		if latency > stats.MaxLatency {
+			stats.MaxLatency = latency
+		}
+	}
+}
+
+// Snapshot returns a copy of the counters of every method called so far.
+func (m *Metrics) Snapshot() map[string]MethodStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	snapshot := make(map[string]MethodStats, len(m.methods))
+	for method, stats := range m.methods {
+		snapshot[method] = *stats
+	}
+	return snapshot
+}
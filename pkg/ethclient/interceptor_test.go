@@ -0,0 +1,81 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+	var tagHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tagHeader = r.Header.Get("X-Request-Tags")
+		var req RequestBody
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x10"}`, req.ID)
+	}))
+	defer server.Close()
+
+	var order []string
+	trace := func(name string) Interceptor {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				order = append(order, name+" "+req.Tags["component"])
+				resp, err := next(ctx, req)
+				order = append(order, fmt.Sprintf("%s %d", name, resp.Size()))
+				return resp, err
+			}
+		}
+	}
+	// Fault injection failing eth_chainId without reaching the node.
+	faults := func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			if req.Calls[0].Method == "eth_chainId" {
+				return nil, &RPCError{Code: CodeServerError, Message: "injected"}
+			}
+			return next(ctx, req)
+		}
+	}
+
+	metrics := NewMetrics()
+	client := NewETHClient(server.URL, WithInterceptors(
+		TaggingInterceptor("X-Request-Tags", map[string]string{"app": "parser"}),
+		LoggingInterceptor(),
+		metrics.Interceptor(),
+		trace("outer"),
+		trace("inner"),
+		faults,
+	))
+
+	ctx := WithRequestTags(context.Background(), map[string]string{"component": "scanner"})
+	if _, err := client.BlockNumber(ctx); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := []string{"outer scanner", "inner scanner", "inner 40", "outer 40"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected interceptors %v, got %v", expected, order)
+	}
+	if tagHeader != "app=parser,component=scanner" {
+		t.Errorf("unexpected tag header %q", tagHeader)
+	}
+
+	var chainID string
+	err := client.call(ctx, &chainID, "eth_chainId")
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "injected" {
+		t.Errorf("expected injected error, got %v", err)
+	}
+
+	stats := metrics.Snapshot()
+	if s := stats[GetBlockbusterMethod]; s.Calls != 1 || s.Errors != 0 || s.Bytes != 40 {
+		t.Errorf("unexpected %s stats %+v", GetBlockbusterMethod, s)
+	}
+	if s := stats["eth_chainId"]; s.Calls != 1 || s.Errors != 1 {
+		t.Errorf("unexpected eth_chainId stats %+v", s)
+	}
+}
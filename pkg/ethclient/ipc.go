@@ -0,0 +1,76 @@
+package ethclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+)
+
+const (
+	// ipcScheme prefixes the socket path in IPC endpoints.
+	ipcScheme = "ipc://"
+
+	// ipcReadBuffer is the size of the read buffer of IPC connections,
+	// large enough to read most responses in a single system call.
+	ipcReadBuffer = 64 << 10
+)
+
+// newIPCTransport returns a transport multiplexing requests and
+// subscriptions over the Unix socket of a local node, e.g. geth.ipc.
+func newIPCTransport(path string) *streamTransport {
+	return newStreamTransport("ipc", path, func(ctx context.Context) (messageConn, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "unix", path)
+		if err != nil {
+			return nil, err
+		}
+		return newIPCConn(conn), nil
+	})
+}
+
+// ipcConn exchanges JSON-RPC messages over a stream connection. Messages
+// are sent newline delimited, received messages are split by the JSON
+// decoder since nodes do not all delimit them.
+type ipcConn struct {
+	conn    net.Conn
+	decoder *json.Decoder
+
+	writeLock sync.Mutex
+}
+
+func newIPCConn(conn net.Conn) *ipcConn {
+	return &ipcConn{
+		conn:    conn,
+		decoder: json.NewDecoder(bufio.NewReaderSize(conn, ipcReadBuffer)),
+	}
+}
+
+// ReadMessage returns the next JSON value read from the connection.
+func (c *ipcConn) ReadMessage() ([]byte, error) {
+	var message json.RawMessage
+	if err := c.decoder.Decode(&message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// WriteMessage sends data followed by a newline.
+func (c *ipcConn) WriteMessage(data []byte) error {
+	message := make([]byte, 0, len(data)+1)
+	message = append(append(message, data...), '\n')
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.conn.Write(message)
+	return err
+}
+
+func (c *ipcConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *ipcConn) abort() {
+	c.conn.Close()
+}
@@ -0,0 +1,143 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newIPCNode starts a local socket server answering eth_getBalance with the
+// address as balance, holding the answer for addresses ending in 1 so
+// responses come back out of order.
+func newIPCNode(t *testing.T) (string, func()) {
+	// Socket paths are limited to about a hundred bytes, too short for
+	// some test temporary directories.
+	dir, err := os.MkdirTemp("", "ipc")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	path := filepath.Join(dir, "node.ipc")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveIPC(conn)
+		}
+	}()
+	return path, func() {
+		listener.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func serveIPC(conn net.Conn) {
+	defer conn.Close()
+	var writeLock sync.Mutex
+	write := func(format string, args ...interface{}) {
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		// No newline, the client must split messages on its own.
+		fmt.Fprintf(conn, format, args...)
+	}
+
+	decoder := json.NewDecoder(conn)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return
+		}
+		if raw[0] == '[' {
+			var reqs []RequestBody
+			json.Unmarshal(raw, &reqs)
+			write(`[{"jsonrpc":"2.0","id":%d,"result":"0x2"},{"jsonrpc":"2.0","id":%d,"result":"0x1"}]`, reqs[1].ID, reqs[0].ID)
+			continue
+		}
+
+		var req RequestBody
+		json.Unmarshal(raw, &req)
+		switch req.Method {
+		case GetBalance:
+			address := req.Params.([]interface{})[0].(string)
+			go func() {
+				if address[len(address)-1] == '1' {
+					time.Sleep(100 * time.Millisecond)
+				}
+				write(`{"jsonrpc":"2.0","id":%d,"result":"%s"}`, req.ID, address)
+			}()
+		case "eth_subscribe":
+			write(`{"jsonrpc":"2.0","id":%d,"result":"0xsub"}`, req.ID)
+			write(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xsub","result":{"number":"0x11"}}}`)
+		case "eth_unsubscribe":
+			write(`{"jsonrpc":"2.0","id":%d,"result":true}`, req.ID)
+		}
+	}
+}
+
+func TestIPCClient(t *testing.T) {
+	path, stop := newIPCNode(t)
+	defer stop()
+
+	client := NewETHClient("ipc://" + path)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The slow request must not hold back the fast one.
+	var wg sync.WaitGroup
+	finished := make(chan string, 2)
+	for _, address := range []string{"0x1", "0x2"} {
+		wg.Add(1)
+		go func(address string) {
+			defer wg.Done()
+			balance, err := client.BalanceAt(ctx, address, LatestBlock)
+			if err != nil {
+				t.Error(err.Error())
+				return
+			}
+			if fmt.Sprintf("0x%x", balance) != address {
+				t.Errorf("expected balance %s, got %s", address, balance)
+			}
+			finished <- address
+		}(address)
+	}
+	wg.Wait()
+	if first := <-finished; first != "0x2" {
+		t.Errorf("expected the fast request to finish first, got %s", first)
+	}
+
+	var first, second string
+	batch := []BatchElem{
+		{Method: GetBalance, Args: []interface{}{"0x1", "latest"}, Result: &first},
+		{Method: GetBalance, Args: []interface{}{"0x2", "latest"}, Result: &second},
+	}
+	if err := client.BatchCall(ctx, batch); err != nil {
+		t.Fatal(err.Error())
+	}
+	if first != "0x1" || second != "0x2" {
+		t.Errorf("unexpected batch results %s, %s", first, second)
+	}
+
+	heads := make(chan *Header, 1)
+	sub, err := client.SubscribeNewHeads(ctx, heads)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer sub.Unsubscribe()
+	if head := receiveHead(t, heads); head.Number != "0x11" {
+		t.Errorf("unexpected head %s", head.Number)
+	}
+}
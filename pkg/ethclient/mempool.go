@@ -0,0 +1,140 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	GetTransactionByHash        = "eth_getTransactionByHash"
+	NewPendingTransactionFilter = "eth_newPendingTransactionFilter"
+	GetFilterChanges            = "eth_getFilterChanges"
+	UninstallFilter             = "eth_uninstallFilter"
+
+	// NewPendingTransactionsSubscription is the eth_subscribe type for the
+	// hashes of the transactions entering the mempool.
+	NewPendingTransactionsSubscription = "newPendingTransactions"
+)
+
+var (
+	// ErrTransactionNotFound is returned when the node knows no transaction
+	// with the hash, neither mined nor pending.
+	ErrTransactionNotFound = errors.New("transaction not found")
+
+	// ErrFilterNotFound is returned when polling a filter the node does not
+	// know, e.g. one expired after not being polled for a while.
+	ErrFilterNotFound = errors.New("filter not found")
+)
+
+// Pending reports whether the transaction is still in the mempool.
+func (tx *ETHTransaction) Pending() bool {
+	return tx.BlockNumber == ""
+}
+
+// TransactionByHash returns the transaction with the given hash, mined or
+// pending.
+func (c *Client) TransactionByHash(ctx context.Context, hash string) (*ETHTransaction, error) {
+	var tx *ETHTransaction
+	if err := c.call(ctx, &tx, GetTransactionByHash, hash); err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, ErrTransactionNotFound
+	}
+	return tx, nil
+}
+
+// TransactionsByHash returns the transactions with the given hashes using
+// a single batch request, in the order of hashes. Unknown transactions are
+// nil.
+func (c *Client) TransactionsByHash(ctx context.Context, hashes []string) ([]*ETHTransaction, error) {
+	txs := make([]*ETHTransaction, len(hashes))
+	batch := make([]BatchElem, len(hashes))
+	for i, hash := range hashes {
+		batch[i] = BatchElem{
+			Method: GetTransactionByHash,
+			Args:   []interface{}{hash},
+			Result: &txs[i],
+		}
+	}
+	if err := c.BatchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("error querying transaction %s: %w", hashes[i], elem.Error)
+		}
+	}
+	return txs, nil
+}
+
+// NewPendingTransactionFilter creates a filter of the transactions
+// entering the mempool and returns its id, to poll with
+// PendingTransactions.
+func (c *Client) NewPendingTransactionFilter(ctx context.Context) (string, error) {
+	var id string
+	if err := c.call(ctx, &id, NewPendingTransactionFilter); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// PendingTransactions returns the hashes of the transactions which entered
+// the mempool since the filter was last polled. It returns an error
+// matching ErrFilterNotFound once the node dropped the filter.
+func (c *Client) PendingTransactions(ctx context.Context, filterID string) ([]string, error) {
+	var hashes []string
+	if err := c.call(ctx, &hashes, GetFilterChanges, filterID); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// UninstallFilter removes the filter from the node, it returns false if
+// the node did not know it.
+func (c *Client) UninstallFilter(ctx context.Context, filterID string) (bool, error) {
+	var removed bool
+	if err := c.call(ctx, &removed, UninstallFilter, filterID); err != nil {
+		return false, err
+	}
+	return removed, nil
+}
+
+// SubscribePendingTransactions subscribes to the hashes of the transactions
+// entering the mempool, delivering them on ch until the subscription ends.
+func (c *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- string) (*ClientSubscription, error) {
+	sub, err := c.Subscribe(ctx, NewPendingTransactionsSubscription)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-sub.quit:
+				return
+			case raw := <-sub.notifications:
+				var hash string
+				if err := json.Unmarshal(raw, &hash); err != nil {
+					continue
+				}
+				select {
+				case ch <- hash:
+				case <-sub.quit:
+					return
+				}
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// isFilterNotFoundMessage reports whether the error message of a node
+// tells that a filter does not exist.
+func isFilterNotFoundMessage(msg string) bool {
+	return strings.Contains(strings.ToLower(msg), "filter not found")
+}
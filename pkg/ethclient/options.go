@@ -0,0 +1,88 @@
+package ethclient
+
+import (
+	"encoding/base64"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultRequestTimeout bounds a single request attempt.
+	defaultRequestTimeout = 30 * time.Second
+	// defaultMaxResponseSize bounds a response body, large enough for
+	// full blocks with receipts.
+	defaultMaxResponseSize = 128 << 20
+)
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithRetryPolicy sets the policy used to retry failed requests. By
+// default requests are not retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http.client = client
+	}
+}
+
+// WithTimeout bounds every request attempt, 0 disables the timeout.
+// Deadlines of the request context still apply.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithHeader adds a header to every request, e.g. a provider API key.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.http.headers.Add(key, value)
+	}
+}
+
+// WithBasicAuth authenticates requests with HTTP basic auth.
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) {
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		c.http.auth = staticAuth("Basic " + credentials)
+	}
+}
+
+// WithBearerToken authenticates requests with a static bearer token.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.http.auth = staticAuth("Bearer " + token)
+	}
+}
+
+// WithJWTSecret authenticates requests with a HS256 JWT signed with the
+// given secret, as required by the authenticated RPC of execution nodes.
+// A fresh token is issued for every request.
+func WithJWTSecret(secret []byte) Option {
+	return func(c *Client) {
+		c.http.auth = jwtAuth(secret)
+	}
+}
+
+// WithMaxResponseSize limits the size of a response body in bytes.
+func WithMaxResponseSize(size int64) Option {
+	return func(c *Client) {
+		c.http.maxResponseSize = size
+	}
+}
+
+// authFunc returns the value of the Authorization header.
+type authFunc func() (string, error)
+
+func staticAuth(value string) authFunc {
+	return func() (string, error) {
+		return value, nil
+	}
+}
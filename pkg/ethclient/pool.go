@@ -0,0 +1,572 @@
+package ethclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/352174109/trustwallet-homework/internal/logs"
+)
+
+const (
+	// ewmaWeight is the weight of the latest observation in the moving
+	// averages of latency and error rate.
+	ewmaWeight = 0.3
+	// errorRatePenalty scales how much the error rate worsens the score
+	// of an endpoint compared to its latency.
+	errorRatePenalty = 10
+)
+
+// PoolConfig configures the endpoint pool of a client.
+type PoolConfig struct {
+	// ProbeInterval is how often every endpoint is queried for its head.
+	ProbeInterval time.Duration
+	// ProbeTimeout bounds a single probe request.
+	ProbeTimeout time.Duration
+	// EjectAfter is the number of consecutive failures after which an
+	// endpoint stops receiving traffic until a probe succeeds again.
+	EjectAfter int
+	// MaxLagBlocks is how far an endpoint may be behind the highest head
+	// seen in the pool before it is avoided.
+	MaxLagBlocks int
+	// FailoverCodes lists the JSON-RPC error codes counted as failures of
+	// the endpoint answering them, the request being sent to the next
+	// one. Rate limit errors always are.
+	FailoverCodes []int
+}
+
+// DefaultPoolConfig returns the pool configuration used by the daemon.
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		ProbeInterval: 15 * time.Second,
+		ProbeTimeout:  5 * time.Second,
+		EjectAfter:    3,
+		MaxLagBlocks:  3,
+		FailoverCodes: DefaultRetryPolicy().RetryableCodes,
+	}
+}
+
+// EndpointStats is a snapshot of the health of a pool endpoint.
+type EndpointStats struct {
+	URL       string
+	Latency   time.Duration
+	ErrorRate float64
+	Head      int
+	Ejected   bool
+	// ChainID is the chain the endpoint was last seen serving, 0 until it
+	// is verified with VerifyChain.
+	ChainID uint64
+}
+
+type poolEndpoint struct {
+	url       string
+	transport transport
+
+	lock                sync.Mutex
+	latency             time.Duration
+	errorRate           float64
+	head                int
+	consecutiveFailures int
+	ejected             bool
+	chainID             uint64
+}
+
+// observe records the outcome of a request sent to the endpoint. It
+// returns true if the endpoint just got ejected.
+func (e *poolEndpoint) observe(latency time.Duration, failed bool, ejectAfter int) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	failure := 0.0
+	if failed {
+		failure = 1
+	}
+	e.errorRate = ewmaWeight*failure + (1-ewmaWeight)*e.errorRate
+
+	if !failed {
+		if e.latency == 0 {
+			e.latency = latency
+		} else {
+			e.latency = time.Duration(ewmaWeight*float64(latency) + (1-ewmaWeight)*float64(e.latency))
+		}
+		e.consecutiveFailures = 0
+		return false
+	}
+
+	e.consecutiveFailures++
+	if !e.ejected && ejectAfter > 0 && e.consecutiveFailures >= ejectAfter {
+		e.ejected = true
+		return true
+	}
+	return false
+}
+
+// score is lower for healthier endpoints.
+func (e *poolEndpoint) score() float64 {
+	return float64(e.latency+time.Millisecond) * (1 + errorRatePenalty*e.errorRate)
+}
+
+func (e *poolEndpoint) stats() EndpointStats {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return EndpointStats{
+		URL:       e.url,
+		Latency:   e.latency,
+		ErrorRate: e.errorRate,
+		Head:      e.head,
+		Ejected:   e.ejected,
+		ChainID:   e.chainID,
+	}
+}
+
+// poolTransport sends every request to the healthiest endpoint of the
+// pool and fails over to the next one when an endpoint does not answer or
+// answers with an error of FailoverCodes. Filters only exist on the
+// endpoint which created them, requests polling or removing a filter are
+// sent to it alone.
+type poolTransport struct {
+	// chainID is the chain endpoints must serve to get traffic, 0 if it
+	// is not verified. It is accessed atomically.
+	chainID uint64
+
+	config    PoolConfig
+	endpoints []*poolEndpoint
+
+	// filters holds the endpoint which created each filter by id.
+	filtersLock sync.Mutex
+	filters     map[string]*poolEndpoint
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewETHPoolClient returns a client spreading its requests over the given
+// endpoints, of any scheme accepted by NewETHClient. Endpoints are probed
+// in the background until the client is closed.
+func NewETHPoolClient(endpoints []string, config PoolConfig, opts ...Option) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no endpoints configured")
+	}
+
+	c := newClient(opts...)
+	transports := make([]transport, len(endpoints))
+	for i, endpoint := range endpoints {
+		transports[i] = newEndpointTransport(endpoint, c.http)
+	}
+	c.transport = newPoolTransport(endpoints, transports, config)
+	return c, nil
+}
+
+func newPoolTransport(urls []string, transports []transport, config PoolConfig) *poolTransport {
+	p := &poolTransport{config: config, filters: make(map[string]*poolEndpoint)}
+	for i, url := range urls {
+		p.endpoints = append(p.endpoints, &poolEndpoint{url: url, transport: transports[i]})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	if config.ProbeInterval > 0 {
+		p.wg.Add(1)
+		go p.probeLoop(ctx)
+	}
+	return p
+}
+
+func (p *poolTransport) roundTrip(ctx context.Context, body []byte) ([]byte, error) {
+	call, ok := filterCall(body)
+	if !ok {
+		raw, _, err := p.failover(ctx, body)
+		return raw, err
+	}
+	if filterID, ok := call.filterID(); ok {
+		if endpoint := p.filterEndpoint(filterID); endpoint != nil {
+			return p.sendPinned(ctx, endpoint, call.Method, filterID, body)
+		}
+		raw, _, err := p.failover(ctx, body)
+		return raw, err
+	}
+
+	raw, endpoint, err := p.failover(ctx, body)
+	if endpoint != nil {
+		var resp ResponseBody
+		var filterID string
+		if json.Unmarshal(raw, &resp) == nil && resp.Error == nil && json.Unmarshal(resp.Result, &filterID) == nil {
+			p.filtersLock.Lock()
+			p.filters[filterID] = endpoint
+			p.filtersLock.Unlock()
+		}
+	}
+	return raw, err
+}
+
+// failover sends the request to the healthiest endpoint, then to the next
+// ones while they fail. It returns the endpoint which answered, nil if
+// none did successfully.
+func (p *poolTransport) failover(ctx context.Context, body []byte) ([]byte, *poolEndpoint, error) {
+	var lastErr error
+	var lastRaw []byte
+	tried := make(map[*poolEndpoint]bool, len(p.endpoints))
+	for len(tried) < len(p.endpoints) {
+		endpoint := p.pick(tried)
+		if endpoint == nil {
+			break
+		}
+		tried[endpoint] = true
+
+		start := time.Now()
+		raw, err := endpoint.transport.roundTrip(ctx, body)
+		if err != nil && ctx.Err() != nil {
+			// The caller gave up, that says nothing about the endpoint.
+			return nil, nil, err
+		}
+		if err == nil {
+			err = p.rpcFailure(raw)
+		} else {
+			raw = nil
+		}
+		if endpoint.observe(time.Since(start), err != nil, p.config.EjectAfter) {
+			logs.CtxWarn(ctx, "endpoint [%s] ejected from pool: %s", endpoint.url, err)
+		}
+		if err == nil {
+			return raw, endpoint, nil
+		}
+
+		logs.CtxDebug(ctx, "endpoint [%s] failed, trying next: %s", endpoint.url, err)
+		lastErr, lastRaw = err, raw
+	}
+	if lastRaw != nil {
+		// Every endpoint failed, the caller decodes the error of the last
+		// one.
+		return lastRaw, nil, nil
+	}
+	if lastErr == nil {
+		return nil, nil, fmt.Errorf("%w: no endpoint serves chain %d", ErrChainMismatch, atomic.LoadUint64(&p.chainID))
+	}
+	return nil, nil, lastErr
+}
+
+// sendPinned sends a request about a filter to the endpoint which created
+// it. The filter is forgotten once removed or unknown to the endpoint, or
+// if the endpoint does not answer, which fails with ErrFilterNotFound for
+// the caller to create it again on another endpoint.
+func (p *poolTransport) sendPinned(ctx context.Context, endpoint *poolEndpoint, method, filterID string, body []byte) ([]byte, error) {
+	start := time.Now()
+	raw, err := endpoint.transport.roundTrip(ctx, body)
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	if endpoint.observe(time.Since(start), err != nil, p.config.EjectAfter) {
+		logs.CtxWarn(ctx, "endpoint [%s] ejected from pool: %s", endpoint.url, err)
+	}
+
+	var resp ResponseBody
+	gone := err == nil && json.Unmarshal(raw, &resp) == nil && resp.Error != nil && errors.Is(resp.Error, ErrFilterNotFound)
+	if err != nil || gone || method == UninstallFilter {
+		p.filtersLock.Lock()
+		delete(p.filters, filterID)
+		p.filtersLock.Unlock()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: endpoint [%s] of filter %s failed: %v", ErrFilterNotFound, endpoint.url, filterID, err)
+	}
+	return raw, nil
+}
+
+// filterEndpoint returns the endpoint which created the filter, nil if
+// unknown.
+func (p *poolTransport) filterEndpoint(filterID string) *poolEndpoint {
+	p.filtersLock.Lock()
+	defer p.filtersLock.Unlock()
+	return p.filters[filterID]
+}
+
+// filterMethods are the methods creating a filter, and the ones taking its
+// id as first param.
+var filterMethods = map[string]bool{
+	NewPendingTransactionFilter: true,
+	"eth_newBlockFilter":        true,
+	"eth_newFilter":             true,
+	GetFilterChanges:            false,
+	"eth_getFilterLogs":         false,
+	UninstallFilter:             false,
+}
+
+// filterCall returns the call of a single request creating or using a
+// filter.
+func filterCall(body []byte) (wireCall, bool) {
+	if !bytes.Contains(body, []byte("Filter")) {
+		return wireCall{}, false
+	}
+	var calls []wireCall
+	if batch, err := decodeWire(body, &calls); err != nil || batch || len(calls) != 1 {
+		return wireCall{}, false
+	}
+	if _, ok := filterMethods[calls[0].Method]; !ok {
+		return wireCall{}, false
+	}
+	return calls[0], true
+}
+
+// filterID returns the id of the filter used by the call, false for calls
+// creating a filter.
+func (w wireCall) filterID() (string, bool) {
+	if filterMethods[w.Method] {
+		return "", false
+	}
+	var params []string
+	if err := json.Unmarshal(w.Params, &params); err != nil || len(params) == 0 {
+		return "", false
+	}
+	return params[0], true
+}
+
+// rpcFailure returns the first error of the response in raw the endpoint
+// is blamed for, nil if there is none. A batch fails over as a whole.
+func (p *poolTransport) rpcFailure(raw []byte) error {
+	if !bytes.Contains(raw, []byte(`"error"`)) {
+		return nil
+	}
+
+	var responses []struct {
+		Error *RPCError `json:"error"`
+	}
+	if _, err := decodeWire(raw, &responses); err != nil {
+		return nil
+	}
+
+	policy := RetryPolicy{RetryableCodes: p.config.FailoverCodes}
+	for _, resp := range responses {
+		if resp.Error != nil && policy.retryable(resp.Error) {
+			return resp.Error
+		}
+	}
+	return nil
+}
+
+// pick returns the healthiest endpoint not in excluded. Ejected endpoints
+// and endpoints lagging behind the highest known head are only used when
+// nothing else is left, endpoints not serving the verified chain never.
+// It returns nil if no endpoint is left.
+func (p *poolTransport) pick(excluded map[*poolEndpoint]bool) *poolEndpoint {
+	chainID := atomic.LoadUint64(&p.chainID)
+	maxHead := 0
+	for _, endpoint := range p.endpoints {
+		endpoint.lock.Lock()
+		if !endpoint.ejected && endpoint.head > maxHead {
+			maxHead = endpoint.head
+		}
+		endpoint.lock.Unlock()
+	}
+
+	var best, fallback *poolEndpoint
+	var bestScore, fallbackScore float64
+	for _, endpoint := range p.endpoints {
+		if excluded[endpoint] {
+			continue
+		}
+
+		endpoint.lock.Lock()
+		score := endpoint.score()
+		healthy := !endpoint.ejected && endpoint.head+p.config.MaxLagBlocks >= maxHead
+		wrongChain := chainID != 0 && endpoint.chainID != chainID
+		endpoint.lock.Unlock()
+
+		if wrongChain {
+			continue
+		}
+		if healthy && (best == nil || score < bestScore) {
+			best, bestScore = endpoint, score
+		}
+		if fallback == nil || score < fallbackScore {
+			fallback, fallbackScore = endpoint, score
+		}
+	}
+
+	if best != nil {
+		return best
+	}
+	return fallback
+}
+
+func (p *poolTransport) probeLoop(ctx context.Context) {
+	defer p.wg.Done()
+
+	p.probe(ctx)
+	ticker := time.NewTicker(p.config.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.probe(ctx)
+		}
+	}
+}
+
+// probe queries the head of every endpoint, updating its health and
+// bringing ejected endpoints back once they answer again. The chain of
+// every endpoint is checked too once it was verified with VerifyChain.
+func (p *poolTransport) probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, endpoint := range p.endpoints {
+		wg.Add(1)
+		go func(endpoint *poolEndpoint) {
+			defer wg.Done()
+
+			probeCtx := ctx
+			if p.config.ProbeTimeout > 0 {
+				var cancel context.CancelFunc
+				probeCtx, cancel = context.WithTimeout(ctx, p.config.ProbeTimeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			head, err := probeHead(probeCtx, endpoint.transport)
+			if ctx.Err() != nil {
+				return
+			}
+			endpoint.observe(time.Since(start), err != nil, p.config.EjectAfter)
+			if err != nil {
+				logs.CtxDebug(ctx, "probing endpoint [%s] failed: %s", endpoint.url, err)
+				return
+			}
+			if chainID := atomic.LoadUint64(&p.chainID); chainID != 0 {
+				if err := p.verifyEndpoint(probeCtx, endpoint, chainID); err != nil {
+					logs.CtxDebug(ctx, "verifying chain of endpoint [%s] failed: %s", endpoint.url, err)
+					return
+				}
+			}
+
+			endpoint.lock.Lock()
+			endpoint.head = head
+			reinstated := endpoint.ejected
+			endpoint.ejected = false
+			endpoint.lock.Unlock()
+			if reinstated {
+				logs.CtxInfo(ctx, "endpoint [%s] back in pool at head %d", endpoint.url, head)
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+}
+
+// verifyChain makes the endpoints serving another chain than chainID stop
+// receiving traffic. It fails if none of them serves it.
+func (p *poolTransport) verifyChain(ctx context.Context, chainID uint64) error {
+	atomic.StoreUint64(&p.chainID, chainID)
+
+	errs := make([]error, len(p.endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range p.endpoints {
+		wg.Add(1)
+		go func(i int, endpoint *poolEndpoint) {
+			defer wg.Done()
+			errs[i] = p.verifyEndpoint(ctx, endpoint, chainID)
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	var lastErr error
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, ErrChainMismatch), lastErr == nil:
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// verifyEndpoint records the chain served by the endpoint and returns
+// ErrChainMismatch if it is not chainID.
+func (p *poolTransport) verifyEndpoint(ctx context.Context, endpoint *poolEndpoint, chainID uint64) error {
+	served, err := chainIDOf(func(result interface{}, method string) error {
+		return probeCall(ctx, endpoint.transport, result, method)
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint.lock.Lock()
+	switched := endpoint.chainID != served
+	endpoint.chainID = served
+	endpoint.lock.Unlock()
+
+	if served != chainID {
+		if switched {
+			logs.CtxWarn(ctx, "endpoint [%s] serves chain %d instead of %d, ejected from pool", endpoint.url, served, chainID)
+		}
+		return fmt.Errorf("%w: endpoint [%s] serves chain %d, expected %d", ErrChainMismatch, endpoint.url, served, chainID)
+	}
+	if switched {
+		logs.CtxInfo(ctx, "endpoint [%s] serves chain %d", endpoint.url, served)
+	}
+	return nil
+}
+
+// EndpointStats returns the health of every endpoint when the client was
+// created with NewETHPoolClient, nil otherwise.
+func (c *Client) EndpointStats() []EndpointStats {
+	if p, ok := c.transport.(*poolTransport); ok {
+		return p.stats()
+	}
+	return nil
+}
+
+func (p *poolTransport) stats() []EndpointStats {
+	stats := make([]EndpointStats, len(p.endpoints))
+	for i, endpoint := range p.endpoints {
+		stats[i] = endpoint.stats()
+	}
+	return stats
+}
+
+func (p *poolTransport) close() {
+	p.cancel()
+	p.wg.Wait()
+	for _, endpoint := range p.endpoints {
+		endpoint.transport.close()
+	}
+}
+
+// probeHead returns the head block number served by the transport.
+func probeHead(ctx context.Context, t transport) (int, error) {
+	var result string
+	if err := probeCall(ctx, t, &result, GetBlockbusterMethod); err != nil {
+		return 0, err
+	}
+	return parseHexInt(result)
+}
+
+// probeCall sends a request without params straight to the transport,
+// bypassing the client retries and interceptors.
+func probeCall(ctx context.Context, t transport, result interface{}, method string) error {
+	body, err := json.Marshal(RequestBody{Jsonrpc: ApiVersion, ID: 1, Method: method, Params: []interface{}{}})
+	if err != nil {
+		return fmt.Errorf("error marshaling json: %v", err)
+	}
+	raw, err := t.roundTrip(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	resp := &ResponseBody{}
+	if err := json.Unmarshal(raw, resp); err != nil {
+		return fmt.Errorf("error decoding response body: %v", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("error decoding result: %v", err)
+	}
+	return nil
+}
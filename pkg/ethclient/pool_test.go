@@ -0,0 +1,243 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newHeadServer serves eth_blockNumber with the given head and counts the
+// requests it receives. A failing server answers everything with 503.
+func newHeadServer(head int, failing *int32, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if atomic.LoadInt32(failing) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":"0x%x"}`, head)
+	}))
+}
+
+// newFilterServer creates the filter id and answers eth_getFilterChanges
+// for it, and eth_blockNumber. A down server answers everything with 503,
+// a lagging one only eth_blockNumber.
+func newFilterServer(id string, state *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int      `json:"id"`
+			Method string   `json:"method"`
+			Params []string `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case atomic.LoadInt32(state) == serverDown,
+			atomic.LoadInt32(state) == serverLagging && req.Method == GetBlockbusterMethod:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case req.Method == NewPendingTransactionFilter:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"%s"}`, req.ID, id)
+		case req.Method == GetFilterChanges && req.Params[0] == id:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":[]}`, req.ID)
+		case req.Method == GetFilterChanges:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32000,"message":"filter not found"}}`, req.ID)
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x64"}`, req.ID)
+		}
+	}))
+}
+
+// States of a filter server.
+const (
+	serverUp = iota
+	serverLagging
+	serverDown
+)
+
+func TestPool(t *testing.T) {
+	config := PoolConfig{EjectAfter: 2, MaxLagBlocks: 3}
+
+	t.Run("Failover", func(t *testing.T) {
+		var badFailing, goodFailing, badCalls, goodCalls int32 = 1, 0, 0, 0
+		bad := newHeadServer(100, &badFailing, &badCalls)
+		defer bad.Close()
+		good := newHeadServer(100, &goodFailing, &goodCalls)
+		defer good.Close()
+
+		client, err := NewETHPoolClient([]string{bad.URL, good.URL}, config)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer client.Close()
+
+		for i := 0; i < 5; i++ {
+			if _, err := client.BlockNumber(context.Background()); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+		if atomic.LoadInt32(&badCalls) != 1 {
+			t.Errorf("expected traffic to move off the failing endpoint, got %d calls", badCalls)
+		}
+		if stats := client.EndpointStats(); stats[0].ErrorRate == 0 || stats[1].ErrorRate != 0 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("AvoidsLaggingEndpoints", func(t *testing.T) {
+		var failing, laggingCalls, headCalls int32
+		lagging := newHeadServer(50, &failing, &laggingCalls)
+		defer lagging.Close()
+		head := newHeadServer(100, &failing, &headCalls)
+		defer head.Close()
+
+		client, err := NewETHPoolClient([]string{lagging.URL, head.URL}, config)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer client.Close()
+		client.transport.(*poolTransport).probe(context.Background())
+		atomic.StoreInt32(&laggingCalls, 0)
+
+		for i := 0; i < 5; i++ {
+			blockNumber, err := client.BlockNumber(context.Background())
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if blockNumber != 100 {
+				t.Errorf("expected head 100, got %d", blockNumber)
+			}
+		}
+		if atomic.LoadInt32(&laggingCalls) != 0 {
+			t.Errorf("expected no traffic to the lagging endpoint, got %d", laggingCalls)
+		}
+	})
+
+	t.Run("FailoverOnRPCErrors", func(t *testing.T) {
+		var limitedCalls, goodFailing, goodCalls int32
+		limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&limitedCalls, 1)
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"daily request count exceeded"}}`)
+		}))
+		defer limited.Close()
+		good := newHeadServer(100, &goodFailing, &goodCalls)
+		defer good.Close()
+
+		config := config
+		config.FailoverCodes = DefaultRetryPolicy().RetryableCodes
+		client, err := NewETHPoolClient([]string{limited.URL, good.URL}, config)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer client.Close()
+
+		for i := 0; i < 5; i++ {
+			if blockNumber, err := client.BlockNumber(context.Background()); err != nil || blockNumber != 100 {
+				t.Fatalf("unexpected block number %d: %v", blockNumber, err)
+			}
+		}
+		if calls := atomic.LoadInt32(&limitedCalls); calls > int32(config.EjectAfter) {
+			t.Errorf("expected traffic to move off the limited endpoint, got %d calls", calls)
+		}
+		if stats := client.EndpointStats(); stats[0].ErrorRate == 0 || stats[1].ErrorRate != 0 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+
+		// Other errors are the caller's, they do not count against the
+		// endpoint.
+		var reverted int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&reverted, 1)
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"execution reverted"}}`)
+		}))
+		defer server.Close()
+		client, _ = NewETHPoolClient([]string{server.URL, good.URL}, config)
+		defer client.Close()
+		if _, err := client.BlockNumber(context.Background()); !errors.Is(err, ErrExecutionReverted) {
+			t.Errorf("expected ErrExecutionReverted, got %v", err)
+		}
+		if stats := client.EndpointStats(); stats[0].ErrorRate != 0 || atomic.LoadInt32(&reverted) != 1 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("AllEndpointsFailWithRPCErrors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"internal error"}}`)
+		}))
+		defer server.Close()
+
+		config := config
+		config.FailoverCodes = DefaultRetryPolicy().RetryableCodes
+		client, _ := NewETHPoolClient([]string{server.URL}, config)
+		defer client.Close()
+		var rpcErr *RPCError
+		if _, err := client.BlockNumber(context.Background()); !errors.As(err, &rpcErr) || rpcErr.Code != CodeInternalError {
+			t.Errorf("expected the internal error of the node, got %v", err)
+		}
+	})
+
+	t.Run("PinsFilters", func(t *testing.T) {
+		var firstState, secondState int32 = serverUp, serverUp
+		first := newFilterServer("0x1", &firstState)
+		defer first.Close()
+		second := newFilterServer("0x2", &secondState)
+		defer second.Close()
+
+		client, _ := NewETHPoolClient([]string{first.URL, second.URL}, config)
+		defer client.Close()
+		ctx := context.Background()
+		id, err := client.NewPendingTransactionFilter(ctx)
+		if err != nil || id != "0x1" {
+			t.Fatalf("expected the filter of the first endpoint, got %s: %v", id, err)
+		}
+
+		// Traffic moves to the second endpoint, the filter is still polled
+		// on the first one.
+		atomic.StoreInt32(&firstState, serverLagging)
+		for i := 0; i < 3; i++ {
+			if _, err := client.BlockNumber(ctx); err != nil {
+				t.Fatal(err.Error())
+			}
+			if _, err := client.PendingTransactions(ctx, id); err != nil {
+				t.Fatalf("expected the filter to be polled on its endpoint: %v", err)
+			}
+		}
+
+		// The filter is lost with its endpoint and created on the other one.
+		atomic.StoreInt32(&firstState, serverDown)
+		if _, err := client.PendingTransactions(ctx, id); !errors.Is(err, ErrFilterNotFound) {
+			t.Errorf("expected ErrFilterNotFound, got %v", err)
+		}
+		if id, err := client.NewPendingTransactionFilter(ctx); err != nil || id != "0x2" {
+			t.Errorf("expected the filter of the second endpoint, got %s: %v", id, err)
+		}
+	})
+
+	t.Run("AllEndpointsDown", func(t *testing.T) {
+		var failing, calls int32 = 1, 0
+		server := newHeadServer(100, &failing, &calls)
+		defer server.Close()
+
+		client, _ := NewETHPoolClient([]string{server.URL}, config)
+		defer client.Close()
+		for i := 0; i < 2; i++ {
+			if _, err := client.BlockNumber(context.Background()); err == nil {
+				t.Error("expected error")
+			}
+		}
+		if stats := client.EndpointStats(); !stats[0].Ejected {
+			t.Errorf("expected endpoint to be ejected, got %+v", stats[0])
+		}
+
+		// Once the endpoint recovers a probe brings it back.
+		atomic.StoreInt32(&failing, 0)
+		client.transport.(*poolTransport).probe(context.Background())
+		if stats := client.EndpointStats(); stats[0].Ejected || stats[0].Head != 100 {
+			t.Errorf("expected endpoint to be reinstated, got %+v", stats[0])
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package ethclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig configures the client side rate limiter.
+type RateLimitConfig struct {
+	// Rate is the number of units refilled per second. Without Costs
+	// every request costs one unit, and Rate is in requests per second.
+	Rate float64
+	// Burst is the number of units which can be spent at once after an
+	// idle period, Rate if 0.
+	Burst float64
+	// Costs is the cost in units of each method, e.g. the compute units
+	// of the provider, see DefaultComputeUnits. Requests of a batch add
+	// up. Methods not listed cost DefaultCost.
+	Costs       map[string]float64
+	DefaultCost float64
+	// MaxConcurrent caps the number of requests in flight, 0 means no cap.
+	MaxConcurrent int
+}
+
+// DefaultComputeUnits returns method costs modeled after the compute unit
+// tables published by hosted providers. Fetching blocks with their
+// transactions, receipts, logs or traces costs more than the head number.
+func DefaultComputeUnits() map[string]float64 {
+	return map[string]float64{
+		GetChainID:                          1,
+		NetVersion:                          1,
+		GetBlockbusterMethod:                10,
+		GetBlockByNumber:                    16,
+		GetBlockByHash:                      16,
+		GetUncleByBlockHashAndIndex:         15,
+		GetBlockTransactionCountByNumber:    10,
+		GetBlockTransactionCountByHash:      10,
+		GetTransactionByBlockHashAndIndex:   15,
+		GetTransactionByBlockNumberAndIndex: 15,
+		GetUncleCountByBlockHash:            10,
+		GetUncleCountByBlockNumber:          10,
+		Syncing:                             1,
+		GetBlobBaseFee:                      10,
+		NetPeerCount:                        1,
+		NetListening:                        1,
+		GetBalance:                          19,
+		GetTransactionCount:                 26,
+		GetCode:                             26,
+		GetStorageAt:                        17,
+		EthCall:                             26,
+		GetTransactionReceipt:               15,
+		GetBlockReceipts:                    500,
+		GetLogs:                             75,
+		DebugTraceBlockByNumber:             500,
+		TraceBlock:                          500,
+		GetGasPrice:                         19,
+		GetMaxPriorityFeePerGas:             10,
+		GetFeeHistory:                       10,
+		"eth_subscribe":                     10,
+		"eth_unsubscribe":                   10,
+		NewPendingTransactionFilter:         20,
+		GetFilterChanges:                    20,
+		UninstallFilter:                     10,
+	}
+}
+
+// defaultMethodCost is the cost of methods missing from the cost table
+// when DefaultCost is not set.
+const defaultMethodCost = 20
+
+// WithRateLimit limits the rate of the requests sent to the endpoints.
+// Requests wait for their turn unless their context expires first, or
+// would expire before, in which case they fail with an error matching
+// context.DeadlineExceeded. Every attempt of a retried request is
+// limited, requests answered by interceptors are not.
+func WithRateLimit(config RateLimitConfig) Option {
+	return func(c *Client) {
+		c.limiter = newRateLimiter(config)
+	}
+}
+
+// rateLimiter is a token bucket. Waiting requests reserve their tokens
+// upfront, leaving the bucket in debt, so they are served in order.
+type rateLimiter struct {
+	config RateLimitConfig
+	slots  chan struct{}
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	if config.Burst <= 0 {
+		config.Burst = config.Rate
+	}
+	if config.DefaultCost <= 0 {
+		config.DefaultCost = defaultMethodCost
+	}
+	l := &rateLimiter{
+		config: config,
+		tokens: config.Burst,
+		last:   time.Now(),
+	}
+	if config.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, config.MaxConcurrent)
+	}
+	return l
+}
+
+// cost returns the number of units the request consumes.
+func (l *rateLimiter) cost(req *Request) float64 {
+	if l.config.Costs == nil {
+		return 1
+	}
+	total := 0.0
+	for _, call := range req.Calls {
+		cost, ok := l.config.Costs[call.Method]
+		if !ok {
+			cost = l.config.DefaultCost
+		}
+		total += cost
+	}
+	return total
+}
+
+// acquire waits until the request may be sent. The returned function
+// must be called once it completed.
+func (l *rateLimiter) acquire(ctx context.Context, req *Request) (func(), error) {
+	if l.config.Rate > 0 {
+		if err := l.wait(ctx, l.cost(req)); err != nil {
+			return nil, err
+		}
+	}
+	if l.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *rateLimiter) wait(ctx context.Context, cost float64) error {
+	l.lock.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.config.Rate
+	if l.tokens > l.config.Burst {
+		l.tokens = l.config.Burst
+	}
+	l.last = now
+	l.tokens -= cost
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.config.Rate * float64(time.Second))
+	}
+	l.lock.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+		l.refund(cost)
+		return fmt.Errorf("%w: rate limit requires waiting %s", context.DeadlineExceeded, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.refund(cost)
+		return ctx.Err()
+	}
+}
+
+// refund gives back the tokens of a request which gave up waiting.
+func (l *rateLimiter) refund(cost float64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.tokens += cost
+}
@@ -0,0 +1,111 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		var req RequestBody
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":"0x10"}`, req.ID)
+	}))
+	defer server.Close()
+
+	t.Run("requests", func(t *testing.T) {
+		client := NewETHClient(server.URL, WithRateLimit(RateLimitConfig{Rate: 20, Burst: 1}))
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			if _, err := client.BlockNumber(context.Background()); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+		// The first request is sent right away, the others every 50ms.
+		if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+			t.Errorf("expected requests to wait for the limiter, took %s", elapsed)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		client := NewETHClient(server.URL, WithRateLimit(RateLimitConfig{Rate: 1, Burst: 1}))
+		if _, err := client.BlockNumber(context.Background()); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := client.BlockNumber(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Errorf("expected to fail without waiting, took %s", elapsed)
+		}
+	})
+
+	t.Run("concurrency", func(t *testing.T) {
+		atomic.StoreInt32(&maxInFlight, 0)
+		client := NewETHClient(server.URL, WithRateLimit(RateLimitConfig{MaxConcurrent: 2}))
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := client.BlockNumber(context.Background()); err != nil {
+					t.Error(err.Error())
+				}
+			}()
+		}
+		wg.Wait()
+		if max := atomic.LoadInt32(&maxInFlight); max != 2 {
+			t.Errorf("expected 2 requests in flight at most, got %d", max)
+		}
+	})
+}
+
+func TestRateLimitCosts(t *testing.T) {
+	limiter := newRateLimiter(RateLimitConfig{Rate: 100, Costs: DefaultComputeUnits(), DefaultCost: 5})
+	tests := []struct {
+		methods []string
+		cost    float64
+	}{
+		{[]string{GetBlockbusterMethod}, 10},
+		{[]string{GetBlockByNumber}, 16},
+		{[]string{GetBlockByNumber, GetBlockByNumber, GetTransactionReceipt}, 47},
+		{[]string{"eth_unknown"}, 5},
+	}
+	for _, test := range tests {
+		req := &Request{}
+		for _, method := range test.methods {
+			req.Calls = append(req.Calls, RequestBody{Method: method})
+		}
+		if cost := limiter.cost(req); cost != test.cost {
+			t.Errorf("expected %v to cost %v, got %v", test.methods, test.cost, cost)
+		}
+	}
+
+	if cost := newRateLimiter(RateLimitConfig{Rate: 1}).cost(&Request{Calls: make([]RequestBody, 3)}); cost != 1 {
+		t.Errorf("expected a request to cost 1 without costs, got %v", cost)
+	}
+}
@@ -0,0 +1,70 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	GetTransactionReceipt = "eth_getTransactionReceipt"
+	GetBlockReceipts      = "eth_getBlockReceipts"
+)
+
+// ErrReceiptNotFound is returned when the node has no receipt for a
+// transaction, e.g. it is still pending or unknown.
+var ErrReceiptNotFound = errors.New("receipt not found")
+
+// TransactionReceipt returns the receipt of the transaction with the given
+// hash.
+func (c *Client) TransactionReceipt(ctx context.Context, hash string) (*Receipt, error) {
+	var receipt *Receipt
+	if err := c.call(ctx, &receipt, GetTransactionReceipt, hash); err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, ErrReceiptNotFound
+	}
+	return receipt, nil
+}
+
+// TransactionReceipts returns the receipts of the given transactions using
+// a single batch request, in the order of hashes.
+func (c *Client) TransactionReceipts(ctx context.Context, hashes []string) ([]*Receipt, error) {
+	receipts := make([]*Receipt, len(hashes))
+	batch := make([]BatchElem, len(hashes))
+	for i, hash := range hashes {
+		batch[i] = BatchElem{
+			Method: GetTransactionReceipt,
+			Args:   []interface{}{hash},
+			Result: &receipts[i],
+		}
+	}
+	if err := c.BatchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	for i, elem := range batch {
+		if elem.Error == nil && receipts[i] == nil {
+			elem.Error = ErrReceiptNotFound
+		}
+		if elem.Error != nil {
+			return nil, fmt.Errorf("error querying receipt %s: %w", hashes[i], elem.Error)
+		}
+	}
+	return receipts, nil
+}
+
+// BlockReceipts returns the receipts of all transactions of the referenced
+// block. Not every node supports eth_getBlockReceipts, in that case the
+// returned error matches a CodeMethodNotFound RPCError.
+func (c *Client) BlockReceipts(ctx context.Context, block BlockRef) ([]*Receipt, error) {
+	var receipts []*Receipt
+	if err := c.call(ctx, &receipts, GetBlockReceipts, block.arg()); err != nil {
+		return nil, err
+	}
+	if receipts == nil {
+		return nil, ErrBlockNotFound
+	}
+	return receipts, nil
+}
@@ -0,0 +1,179 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Multiplier:     2,
+		RetryableCodes: []int{CodeServerError},
+	}
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("RecoversFromServerErrors", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&calls, 1) {
+			case 1:
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			case 2:
+				fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"upstream timeout"}}`)
+			default:
+				fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x10"}`)
+			}
+		}))
+		defer server.Close()
+
+		client := NewETHClient(server.URL, WithRetryPolicy(testRetryPolicy()))
+		blockNumber, err := client.BlockNumber(context.Background())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if blockNumber != 16 || atomic.LoadInt32(&calls) != 3 {
+			t.Errorf("unexpected block number %d after %d calls", blockNumber, calls)
+		}
+	})
+
+	t.Run("StopsAtMaxAttempts", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := NewETHClient(server.URL, WithRetryPolicy(testRetryPolicy()))
+		_, err := client.BlockNumber(context.Background())
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("expected ErrRateLimited, got %v", err)
+		}
+		if atomic.LoadInt32(&calls) != 3 {
+			t.Errorf("expected 3 attempts, got %d", calls)
+		}
+	})
+
+	t.Run("CapsRetryAfter", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("Retry-After", "3600")
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x10"}`)
+		}))
+		defer server.Close()
+
+		client := NewETHClient(server.URL, WithRetryPolicy(testRetryPolicy()))
+		start := time.Now()
+		if _, err := client.BlockNumber(context.Background()); err != nil {
+			t.Fatal(err.Error())
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("Retry-After not capped at MaxBackoff, waited %s", time.Since(start))
+		}
+	})
+
+	t.Run("FailsBeforeDeadline", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("Retry-After", "10")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		policy := testRetryPolicy()
+		policy.MaxBackoff = time.Minute
+		client := NewETHClient(server.URL, WithRetryPolicy(policy))
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		start := time.Now()
+		if _, err := client.BlockNumber(ctx); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("expected ErrRateLimited, got %v", err)
+		}
+		if time.Since(start) > time.Second || atomic.LoadInt32(&calls) != 1 {
+			t.Errorf("expected to fail right away, waited %s for %d attempts", time.Since(start), calls)
+		}
+	})
+
+	t.Run("DoesNotRetryPermanentErrors", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`)
+		}))
+		defer server.Close()
+
+		client := NewETHClient(server.URL, WithRetryPolicy(testRetryPolicy()))
+		if _, err := client.BlockNumber(context.Background()); err == nil {
+			t.Error("expected error")
+		}
+		if atomic.LoadInt32(&calls) != 1 {
+			t.Errorf("expected 1 attempt, got %d", calls)
+		}
+	})
+
+	t.Run("StopsOnContextDone", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		policy := testRetryPolicy()
+		policy.MaxAttempts = 100
+		policy.InitialBackoff = time.Hour
+		policy.MaxBackoff = time.Hour
+		client := NewETHClient(server.URL, WithRetryPolicy(policy))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := client.BlockNumber(ctx); err == nil {
+			t.Error("expected error")
+		}
+		if time.Since(start) > time.Second {
+			t.Error("retry did not stop on context cancellation")
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.5}
+	for attempt, base := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		delay := policy.backoff(attempt)
+		if delay < base/2 || delay > base*3/2 {
+			t.Errorf("attempt %d: delay %s out of range around %s", attempt, delay, base)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 9, 17, 1, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"Tue, 17 Sep 2024 01:00:30 GMT": 30 * time.Second,
+		"Tue, 17 Sep 2024 00:59:00 GMT": 0,
+		"soon":                          0,
+	}
+	for value, expected := range cases {
+		if got := parseRetryAfter(value, now); got != expected {
+			t.Errorf("parseRetryAfter(%q) = %s, expected %s", value, got, expected)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ethclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+const (
+	GetBalance          = "eth_getBalance"
+	GetTransactionCount = "eth_getTransactionCount"
+	GetCode             = "eth_getCode"
+	GetStorageAt        = "eth_getStorageAt"
+)
+
+// BalanceAt returns the balance in wei of the account at the given block.
+func (c *Client) BalanceAt(ctx context.Context, address string, block BlockRef) (*big.Int, error) {
+	var result string
+	if err := c.call(ctx, &result, GetBalance, address, block.arg()); err != nil {
+		return nil, err
+	}
+
+	balance, err := parseHexBig(result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing balance: %v", err)
+	}
+	return balance, nil
+}
+
+// NonceAt returns the number of transactions sent by the account at the
+// given block.
+func (c *Client) NonceAt(ctx context.Context, address string, block BlockRef) (uint64, error) {
+	var result string
+	if err := c.call(ctx, &result, GetTransactionCount, address, block.arg()); err != nil {
+		return 0, err
+	}
+
+	nonce, err := parseHexUint64(result)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing nonce: %v", err)
+	}
+	return nonce, nil
+}
+
+// CodeAt returns the contract code of the account at the given block,
+// empty for externally owned accounts.
+func (c *Client) CodeAt(ctx context.Context, address string, block BlockRef) ([]byte, error) {
+	var result string
+	if err := c.call(ctx, &result, GetCode, address, block.arg()); err != nil {
+		return nil, err
+	}
+
+	code, err := parseHexBytes(result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing code: %v", err)
+	}
+	return code, nil
+}
+
+// StorageAt returns the value of the storage slot of the account at the
+// given block. slot is a 0x-prefixed hex position.
+func (c *Client) StorageAt(ctx context.Context, address, slot string, block BlockRef) ([]byte, error) {
+	var result string
+	if err := c.call(ctx, &result, GetStorageAt, address, slot, block.arg()); err != nil {
+		return nil, err
+	}
+
+	value, err := parseHexBytes(result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing storage: %v", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,499 @@
+package ethclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/352174109/trustwallet-homework/internal/logs"
+)
+
+const (
+	streamDialTimeout         = 10 * time.Second
+	streamMinReconnectBackoff = time.Second
+	streamMaxReconnectBackoff = 30 * time.Second
+	// streamPingInterval is how often an idle connection supporting pings
+	// is checked.
+	streamPingInterval = 30 * time.Second
+
+	// subscriptionBuffer is the number of notifications queued for a slow
+	// subscriber before new ones are dropped.
+	subscriptionBuffer = 128
+)
+
+var (
+	// ErrNotificationsUnsupported is returned when subscribing through a
+	// transport without push support, like HTTP.
+	ErrNotificationsUnsupported = errors.New("notifications not supported by transport")
+
+	// ErrConnectionLost is delivered on the Err channel of subscriptions
+	// when the connection carrying them drops.
+	ErrConnectionLost = errors.New("connection lost")
+
+	errClientClosed = errors.New("client closed")
+)
+
+// messageConn is a connection exchanging whole JSON-RPC messages, like a
+// WebSocket or a Unix socket.
+type messageConn interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage(data []byte) error
+	// Close closes the connection gracefully.
+	Close() error
+	// abort closes the connection immediately, failing pending reads.
+	abort()
+}
+
+// pinger is implemented by connections which can check the liveness of
+// their peer while idle.
+type pinger interface {
+	Ping() error
+}
+
+// streamCall is a request waiting for its response. When sub is set the
+// request is an eth_subscribe and the subscription is registered as soon
+// as the response is read, before any notification for it.
+type streamCall struct {
+	response chan []byte
+	sub      *ClientSubscription
+}
+
+// streamTransport multiplexes requests and subscriptions over a message
+// connection, matching responses by id. The connection is established in
+// the background and re-established with its subscriptions whenever it
+// drops.
+type streamTransport struct {
+	// name is the kind of connection, used in logs.
+	name     string
+	endpoint string
+	dialer   func(ctx context.Context) (messageConn, error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// internalID numbers the requests issued by the transport itself.
+	// They count down from -1 to never collide with client ids.
+	internalID int32
+
+	lock      sync.Mutex
+	conn      messageConn
+	connected chan struct{}
+	pending   map[int]*streamCall
+	subs      map[*ClientSubscription]struct{}
+	subsByID  map[string]*ClientSubscription
+}
+
+func newStreamTransport(name, endpoint string, dialer func(ctx context.Context) (messageConn, error)) *streamTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &streamTransport{
+		name:      name,
+		endpoint:  endpoint,
+		dialer:    dialer,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		connected: make(chan struct{}),
+		pending:   make(map[int]*streamCall),
+		subs:      make(map[*ClientSubscription]struct{}),
+		subsByID:  make(map[string]*ClientSubscription),
+	}
+	go t.loop()
+	return t
+}
+
+func (t *streamTransport) roundTrip(ctx context.Context, body []byte) ([]byte, error) {
+	return t.send(ctx, body, nil)
+}
+
+func (t *streamTransport) send(ctx context.Context, body []byte, sub *ClientSubscription) ([]byte, error) {
+	ids, err := messageIDs(body)
+	if err != nil {
+		return nil, err
+	}
+	id := ids[0]
+
+	conn, err := t.waitConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	call := &streamCall{response: make(chan []byte, 1), sub: sub}
+	t.lock.Lock()
+	t.pending[id] = call
+	t.lock.Unlock()
+	defer func() {
+		t.lock.Lock()
+		delete(t.pending, id)
+		t.lock.Unlock()
+	}()
+
+	if err := conn.WriteMessage(body); err != nil {
+		conn.abort()
+		return nil, fmt.Errorf("%w: %v", errTransport, err)
+	}
+
+	select {
+	case raw, ok := <-call.response:
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", errTransport, ErrConnectionLost)
+		}
+		return raw, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, errClientClosed
+	}
+}
+
+// waitConn returns the current connection, waiting for it to be
+// established if needed.
+func (t *streamTransport) waitConn(ctx context.Context) (messageConn, error) {
+	for {
+		t.lock.Lock()
+		conn, connected := t.conn, t.connected
+		t.lock.Unlock()
+		if conn != nil {
+			return conn, nil
+		}
+
+		select {
+		case <-connected:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.done:
+			return nil, errClientClosed
+		}
+	}
+}
+
+// loop keeps the connection up until the transport is closed.
+func (t *streamTransport) loop() {
+	defer close(t.done)
+
+	backoff := streamMinReconnectBackoff
+	for {
+		conn, err := t.dial()
+		if err != nil {
+			if t.ctx.Err() != nil {
+				return
+			}
+			logs.CtxWarn(t.ctx, "%s dial [%s] failed, retrying in %s: %s", t.name, t.endpoint, backoff, err)
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > streamMaxReconnectBackoff {
+				backoff = streamMaxReconnectBackoff
+			}
+			continue
+		}
+		backoff = streamMinReconnectBackoff
+
+		logs.CtxInfo(t.ctx, "%s connected to [%s]", t.name, t.endpoint)
+		t.setConn(conn)
+		go t.resubscribe()
+		stopPing := t.keepAlive(conn)
+
+		err = t.readLoop(conn)
+		close(stopPing)
+		conn.Close()
+		t.dropConn()
+		if t.ctx.Err() != nil {
+			return
+		}
+		logs.CtxWarn(t.ctx, "%s connection to [%s] lost: %s", t.name, t.endpoint, err)
+	}
+}
+
+func (t *streamTransport) dial() (messageConn, error) {
+	ctx, cancel := context.WithTimeout(t.ctx, streamDialTimeout)
+	defer cancel()
+	return t.dialer(ctx)
+}
+
+func (t *streamTransport) setConn(conn messageConn) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.conn = conn
+	close(t.connected)
+}
+
+// dropConn fails the requests in flight and tells subscribers their
+// subscription is interrupted until the next connection.
+func (t *streamTransport) dropConn() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.conn = nil
+	t.connected = make(chan struct{})
+	for id, call := range t.pending {
+		close(call.response)
+		delete(t.pending, id)
+	}
+	for id := range t.subsByID {
+		delete(t.subsByID, id)
+	}
+	for sub := range t.subs {
+		sub.setID("")
+		sub.deliverErr(ErrConnectionLost)
+	}
+}
+
+// keepAlive pings the connection until the returned channel is closed,
+// if it supports pings.
+func (t *streamTransport) keepAlive(conn messageConn) chan struct{} {
+	stop := make(chan struct{})
+	p, ok := conn.(pinger)
+	if !ok {
+		return stop
+	}
+	go func() {
+		ticker := time.NewTicker(streamPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := p.Ping(); err != nil {
+					conn.abort()
+					return
+				}
+			}
+		}
+	}()
+	return stop
+}
+
+func (t *streamTransport) readLoop(conn messageConn) error {
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		t.dispatch(message)
+	}
+}
+
+// streamMessage covers both responses and subscription notifications.
+type streamMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+func (t *streamTransport) dispatch(raw []byte) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return
+	}
+
+	if raw[0] == '[' {
+		// Batch responses may come in any order, the call is registered
+		// under the id of its first request.
+		ids, err := messageIDs(raw)
+		if err != nil {
+			logs.CtxWarn(t.ctx, "%s: invalid batch response: %s", t.name, err)
+			return
+		}
+		for _, id := range ids {
+			if t.deliver(id, raw, nil) {
+				return
+			}
+		}
+		return
+	}
+
+	msg := &streamMessage{}
+	if err := json.Unmarshal(raw, msg); err != nil {
+		logs.CtxWarn(t.ctx, "%s: invalid message: %s", t.name, err)
+		return
+	}
+	if msg.ID == nil {
+		if msg.Method == "eth_subscription" {
+			t.notify(msg.Params.Subscription, msg.Params.Result)
+		}
+		return
+	}
+	t.deliver(*msg.ID, raw, msg)
+}
+
+// deliver hands the response to the call waiting for id. It returns false
+// if there is no such call.
+func (t *streamTransport) deliver(id int, raw []byte, msg *streamMessage) bool {
+	t.lock.Lock()
+	call, ok := t.pending[id]
+	if ok {
+		delete(t.pending, id)
+		if call.sub != nil && msg != nil && msg.Error == nil {
+			var subID string
+			if err := json.Unmarshal(msg.Result, &subID); err == nil && subID != "" {
+				call.sub.setID(subID)
+				t.subsByID[subID] = call.sub
+			}
+		}
+	}
+	t.lock.Unlock()
+
+	if ok {
+		call.response <- raw
+	}
+	return ok
+}
+
+func (t *streamTransport) notify(subID string, result json.RawMessage) {
+	t.lock.Lock()
+	sub, ok := t.subsByID[subID]
+	t.lock.Unlock()
+	if !ok {
+		return
+	}
+
+	select {
+	case sub.notifications <- result:
+	default:
+		logs.CtxWarn(t.ctx, "subscription [%s] buffer full, dropping notification", subID)
+	}
+}
+
+func (t *streamTransport) subscribe(ctx context.Context, args []interface{}) (*ClientSubscription, error) {
+	sub := newClientSubscription(t, args)
+	if err := t.sendSubscribe(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	t.lock.Lock()
+	t.subs[sub] = struct{}{}
+	t.lock.Unlock()
+	return sub, nil
+}
+
+func (t *streamTransport) sendSubscribe(ctx context.Context, sub *ClientSubscription) error {
+	raw, err := t.send(ctx, t.internalRequest("eth_subscribe", sub.args), sub)
+	if err != nil {
+		return err
+	}
+
+	resp := &ResponseBody{}
+	if err := json.Unmarshal(raw, resp); err != nil {
+		return fmt.Errorf("error decoding response body: %v", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+// resubscribe re-establishes the active subscriptions on a new connection.
+func (t *streamTransport) resubscribe() {
+	t.lock.Lock()
+	subs := make([]*ClientSubscription, 0, len(t.subs))
+	for sub := range t.subs {
+		subs = append(subs, sub)
+	}
+	t.lock.Unlock()
+
+	for _, sub := range subs {
+		ctx, cancel := context.WithTimeout(t.ctx, streamDialTimeout)
+		err := t.sendSubscribe(ctx, sub)
+		cancel()
+		if err != nil {
+			logs.CtxError(t.ctx, "%s: resubscribing %v failed: %s", t.name, sub.args, err)
+			continue
+		}
+		logs.CtxDebug(t.ctx, "%s: resubscribed %v as [%s]", t.name, sub.args, sub.ID())
+	}
+}
+
+func (t *streamTransport) unsubscribe(sub *ClientSubscription) {
+	t.lock.Lock()
+	delete(t.subs, sub)
+	id := sub.ID()
+	if id != "" {
+		delete(t.subsByID, id)
+	}
+	connected := t.conn != nil
+	t.lock.Unlock()
+
+	if id == "" || !connected {
+		return
+	}
+	ctx, cancel := context.WithTimeout(t.ctx, streamDialTimeout)
+	defer cancel()
+	if _, err := t.send(ctx, t.internalRequest("eth_unsubscribe", []interface{}{id}), nil); err != nil {
+		logs.CtxDebug(t.ctx, "%s: unsubscribing [%s] failed: %s", t.name, id, err)
+	}
+}
+
+func (t *streamTransport) internalRequest(method string, params []interface{}) []byte {
+	body, _ := json.Marshal(RequestBody{
+		Jsonrpc: ApiVersion,
+		ID:      int(atomic.AddInt32(&t.internalID, -1)),
+		Method:  method,
+		Params:  params,
+	})
+	return body
+}
+
+func (t *streamTransport) close() {
+	t.cancel()
+	t.lock.Lock()
+	conn := t.conn
+	t.lock.Unlock()
+	if conn != nil {
+		conn.abort()
+	}
+	<-t.done
+
+	t.lock.Lock()
+	subs := t.subs
+	t.subs = make(map[*ClientSubscription]struct{})
+	t.lock.Unlock()
+	for sub := range subs {
+		sub.end()
+	}
+}
+
+// messageIDs returns the ids of a single message or of every message of
+// a batch.
+func messageIDs(raw []byte) ([]int, error) {
+	type message struct {
+		ID int `json:"id"`
+	}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		var msgs []message
+		if err := json.Unmarshal(raw, &msgs); err != nil {
+			return nil, err
+		}
+		if len(msgs) == 0 {
+			return nil, errors.New("empty batch")
+		}
+		ids := make([]int, len(msgs))
+		for i, msg := range msgs {
+			ids[i] = msg.ID
+		}
+		return ids, nil
+	}
+
+	var msg message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return nil, err
+	}
+	return []int{msg.ID}, nil
+}
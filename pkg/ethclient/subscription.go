@@ -0,0 +1,140 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// NewHeadsSubscription is the eth_subscribe type for new chain heads.
+const NewHeadsSubscription = "newHeads"
+
+// subscriber is implemented by transports supporting eth_subscribe.
+type subscriber interface {
+	subscribe(ctx context.Context, args []interface{}) (*ClientSubscription, error)
+
+	unsubscribe(sub *ClientSubscription)
+}
+
+// ClientSubscription is a subscription established with eth_subscribe.
+// It survives reconnections of the underlying transport: the subscription
+// is re-established on the new connection and notifications resume.
+type ClientSubscription struct {
+	transport subscriber
+	args      []interface{}
+
+	notifications chan json.RawMessage
+	errs          chan error
+	quit          chan struct{}
+
+	lock  sync.Mutex
+	id    string
+	ended bool
+}
+
+func newClientSubscription(t subscriber, args []interface{}) *ClientSubscription {
+	return &ClientSubscription{
+		transport:     t,
+		args:          args,
+		notifications: make(chan json.RawMessage, subscriptionBuffer),
+		errs:          make(chan error, 1),
+		quit:          make(chan struct{}),
+	}
+}
+
+// ID returns the id assigned by the node, empty while disconnected.
+func (s *ClientSubscription) ID() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.id
+}
+
+func (s *ClientSubscription) setID(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.id = id
+}
+
+// Notifications returns the raw results of the notifications.
+func (s *ClientSubscription) Notifications() <-chan json.RawMessage {
+	return s.notifications
+}
+
+// Err returns a channel receiving ErrConnectionLost whenever the
+// connection carrying the subscription drops. It is closed once the
+// subscription ends, by Unsubscribe or by closing the client.
+func (s *ClientSubscription) Err() <-chan error {
+	return s.errs
+}
+
+// Unsubscribe ends the subscription.
+func (s *ClientSubscription) Unsubscribe() {
+	s.transport.unsubscribe(s)
+	s.end()
+}
+
+func (s *ClientSubscription) deliverErr(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.ended {
+		return
+	}
+	select {
+	case s.errs <- err:
+	default:
+		// The subscriber has not consumed the previous error yet.
+	}
+}
+
+func (s *ClientSubscription) end() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.ended {
+		return
+	}
+	s.ended = true
+	close(s.quit)
+	close(s.errs)
+}
+
+// Subscribe creates a subscription with eth_subscribe; args are its
+// parameters, starting with the subscription type. It returns
+// ErrNotificationsUnsupported unless the client uses a push transport.
+func (c *Client) Subscribe(ctx context.Context, args ...interface{}) (*ClientSubscription, error) {
+	t, ok := c.transport.(subscriber)
+	if !ok {
+		return nil, ErrNotificationsUnsupported
+	}
+	return t.subscribe(ctx, args)
+}
+
+// SubscribeNewHeads subscribes to the new chain heads, delivering them on
+// ch until the subscription ends.
+func (c *Client) SubscribeNewHeads(ctx context.Context, ch chan<- *Header) (*ClientSubscription, error) {
+	sub, err := c.Subscribe(ctx, NewHeadsSubscription)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-sub.quit:
+				return
+			case raw := <-sub.notifications:
+				header := &Header{}
+				if err := json.Unmarshal(raw, header); err != nil {
+					continue
+				}
+				select {
+				case ch <- header:
+				case <-sub.quit:
+					return
+				}
+			}
+		}
+	}()
+	return sub, nil
+}
@@ -0,0 +1,256 @@
+package ethclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	DebugTraceBlockByNumber = "debug_traceBlockByNumber"
+	TraceBlock              = "trace_block"
+)
+
+// Call types of the frames of a call trace.
+const (
+	CallTypeCall         = "CALL"
+	CallTypeCallCode     = "CALLCODE"
+	CallTypeDelegateCall = "DELEGATECALL"
+	CallTypeStaticCall   = "STATICCALL"
+	CallTypeCreate       = "CREATE"
+	CallTypeCreate2      = "CREATE2"
+	CallTypeSelfDestruct = "SELFDESTRUCT"
+)
+
+// TraceAPI selects the node API used to trace the calls of a block.
+type TraceAPI int
+
+const (
+	// TraceDebug uses debug_traceBlockByNumber with the callTracer,
+	// available on geth, reth, erigon and most providers debug plans.
+	TraceDebug TraceAPI = iota
+	// TraceParity uses trace_block, available on erigon, reth and
+	// nethermind.
+	TraceParity
+)
+
+// CallFrame is a call of the callTracer, with the calls it made.
+type CallFrame struct {
+	Type         string       `json:"type"`
+	From         string       `json:"from"`
+	To           string       `json:"to"`
+	Value        string       `json:"value"`
+	Gas          string       `json:"gas"`
+	GasUsed      string       `json:"gasUsed"`
+	Input        string       `json:"input"`
+	Output       string       `json:"output"`
+	Error        string       `json:"error"`
+	RevertReason string       `json:"revertReason"`
+	Calls        []*CallFrame `json:"calls"`
+}
+
+// TxTrace is the call trace of a transaction. TxHash is only set by nodes
+// recent enough, traces are otherwise in the order of the transactions of
+// the block.
+type TxTrace struct {
+	TxHash string     `json:"txHash"`
+	Result *CallFrame `json:"result"`
+}
+
+// ParityTrace is a single call of a trace_block response.
+type ParityTrace struct {
+	Action struct {
+		CallType string `json:"callType"`
+		From     string `json:"from"`
+		To       string `json:"to"`
+		Value    string `json:"value"`
+		Gas      string `json:"gas"`
+		Input    string `json:"input"`
+		// Set on selfdestruct traces.
+		Address       string `json:"address"`
+		RefundAddress string `json:"refundAddress"`
+		Balance       string `json:"balance"`
+	} `json:"action"`
+	Result *struct {
+		GasUsed string `json:"gasUsed"`
+		Output  string `json:"output"`
+		// Set on create traces.
+		Address string `json:"address"`
+	} `json:"result"`
+	Error           string `json:"error"`
+	TraceAddress    []int  `json:"traceAddress"`
+	TransactionHash string `json:"transactionHash"`
+	Type            string `json:"type"`
+}
+
+// InternalCall is a call made by a contract during the execution of a
+// transaction.
+type InternalCall struct {
+	TxHash string
+	// Path is the position of the call in the call tree of the
+	// transaction, [1, 0] is the first call of the second call made by the
+	// top level call.
+	Path  []int
+	Type  string
+	From  string
+	To    string
+	Value string
+	Gas   string
+	Input string
+	// Error is set when the call or one of its callers failed, its effects
+	// were then reverted.
+	Error string
+}
+
+// TraceBlockCalls returns the callTracer traces of the transactions of the
+// referenced block.
+func (c *Client) TraceBlockCalls(ctx context.Context, block BlockRef) ([]*TxTrace, error) {
+	var traces []*TxTrace
+	tracer := map[string]interface{}{"tracer": "callTracer"}
+	if err := c.call(ctx, &traces, DebugTraceBlockByNumber, block.arg(), tracer); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// TraceBlock returns the trace_block traces of the block with the given
+// number.
+func (c *Client) TraceBlock(ctx context.Context, blockNumber int) ([]*ParityTrace, error) {
+	var traces []*ParityTrace
+	if err := c.call(ctx, &traces, TraceBlock, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	if traces == nil {
+		return nil, ErrBlockNotFound
+	}
+	return traces, nil
+}
+
+// InternalCalls returns the calls made by contracts in the block with the
+// given number, traced with the given API. Top level calls are left out,
+// they are the transactions of the block. txHashes are the hashes of the
+// transactions of the block in order, they are needed by nodes which do
+// not return them along with callTracer traces.
+func (c *Client) InternalCalls(ctx context.Context, blockNumber int, api TraceAPI, txHashes []string) ([]*InternalCall, error) {
+	if api == TraceParity {
+		traces, err := c.TraceBlock(ctx, blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		return parityInternalCalls(traces), nil
+	}
+
+	traces, err := c.TraceBlockCalls(ctx, BlockNumberRef(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+	var calls []*InternalCall
+	for i, trace := range traces {
+		if trace.Result == nil {
+			continue
+		}
+		txHash := trace.TxHash
+		if txHash == "" {
+			if i >= len(txHashes) {
+				return nil, fmt.Errorf("trace %d has no transaction hash", i)
+			}
+			txHash = txHashes[i]
+		}
+		calls = appendInternalCalls(calls, txHash, trace.Result, nil, trace.Result.Error)
+	}
+	return calls, nil
+}
+
+// appendInternalCalls flattens the calls made by frame, depth first.
+func appendInternalCalls(calls []*InternalCall, txHash string, frame *CallFrame, path []int, failed string) []*InternalCall {
+	for i, sub := range frame.Calls {
+		subPath := append(append(make([]int, 0, len(path)+1), path...), i)
+		subFailed := failed
+		if subFailed == "" {
+			subFailed = sub.Error
+		}
+		calls = append(calls, &InternalCall{
+			TxHash: txHash,
+			Path:   subPath,
+			Type:   strings.ToUpper(sub.Type),
+			From:   sub.From,
+			To:     sub.To,
+			Value:  sub.Value,
+			Gas:    sub.Gas,
+			Input:  sub.Input,
+			Error:  subFailed,
+		})
+		calls = appendInternalCalls(calls, txHash, sub, subPath, subFailed)
+	}
+	return calls
+}
+
+// parityInternalCalls converts the nested traces of trace_block. Failures
+// are inherited from the caller, which always precedes its calls.
+func parityInternalCalls(traces []*ParityTrace) []*InternalCall {
+	var calls []*InternalCall
+	failed := make(map[string]string)
+	for _, trace := range traces {
+		if trace.TransactionHash == "" {
+			// Block and uncle rewards.
+			continue
+		}
+
+		path := trace.TraceAddress
+		callerErr := ""
+		if len(path) > 0 {
+			callerErr = failed[pathKey(trace.TransactionHash, path[:len(path)-1])]
+		}
+		callErr := callerErr
+		if callErr == "" {
+			callErr = trace.Error
+		}
+		if callErr != "" {
+			failed[pathKey(trace.TransactionHash, path)] = callErr
+		}
+		if len(path) == 0 {
+			continue
+		}
+
+		call := &InternalCall{
+			TxHash: trace.TransactionHash,
+			Path:   path,
+			From:   trace.Action.From,
+			To:     trace.Action.To,
+			Value:  trace.Action.Value,
+			Gas:    trace.Action.Gas,
+			Input:  trace.Action.Input,
+			Error:  callErr,
+		}
+		switch trace.Type {
+		case "create":
+			call.Type = CallTypeCreate
+			if trace.Result != nil {
+				call.To = trace.Result.Address
+			}
+		case "suicide", "selfdestruct":
+			call.Type = CallTypeSelfDestruct
+			call.From, call.To, call.Value = trace.Action.Address, trace.Action.RefundAddress, trace.Action.Balance
+		default:
+			call.Type = strings.ToUpper(trace.Action.CallType)
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+func pathKey(txHash string, path []int) string {
+	return fmt.Sprint(txHash, path)
+}
+
+// TransfersValue reports whether the call moved ether to another account:
+// delegate, static and code calls never do, whatever their value field
+// says.
+func (c *InternalCall) TransfersValue() bool {
+	switch c.Type {
+	case CallTypeCall, CallTypeCreate, CallTypeCreate2, CallTypeSelfDestruct:
+	default:
+		return false
+	}
+	return c.Error == "" && c.Value != "" && strings.TrimLeft(strings.TrimPrefix(c.Value, "0x"), "0") != ""
+}
@@ -0,0 +1,88 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// callTracerResult is a multisig paying out through a nested call, plus a
+// transfer reverted with its caller. The first trace has no txHash like
+// older nodes answer.
+const callTracerResult = `[
+	{"result":{"type":"CALL","from":"0xa","to":"0xb","value":"0x0","calls":[
+		{"type":"DELEGATECALL","from":"0xb","to":"0xc","value":"0x5","calls":[
+			{"type":"CALL","from":"0xb","to":"0xd","value":"0x5"}
+		]},
+		{"type":"STATICCALL","from":"0xb","to":"0xe"}
+	]}},
+	{"txHash":"0x2","result":{"type":"CALL","from":"0xa","to":"0xb","value":"0x0","calls":[
+		{"type":"CALL","from":"0xb","to":"0xf","value":"0x0","error":"execution reverted","calls":[
+			{"type":"CALL","from":"0xf","to":"0xd","value":"0x7"}
+		]},
+		{"type":"SELFDESTRUCT","from":"0xb","to":"0xd","value":"0x9"}
+	]}}
+]`
+
+const parityTraceResult = `[
+	{"action":{"callType":"call","from":"0xa","to":"0xb","value":"0x0"},"result":{},"traceAddress":[],"transactionHash":"0x1","type":"call"},
+	{"action":{"callType":"delegatecall","from":"0xb","to":"0xc","value":"0x5"},"result":{},"traceAddress":[0],"transactionHash":"0x1","type":"call"},
+	{"action":{"callType":"call","from":"0xb","to":"0xd","value":"0x5"},"result":{},"traceAddress":[0,0],"transactionHash":"0x1","type":"call"},
+	{"action":{"callType":"staticcall","from":"0xb","to":"0xe","value":"0x0"},"result":{},"traceAddress":[1],"transactionHash":"0x1","type":"call"},
+	{"action":{"callType":"call","from":"0xa","to":"0xb","value":"0x0"},"result":{},"traceAddress":[],"transactionHash":"0x2","type":"call"},
+	{"action":{"callType":"call","from":"0xb","to":"0xf","value":"0x0"},"error":"Reverted","traceAddress":[0],"transactionHash":"0x2","type":"call"},
+	{"action":{"callType":"call","from":"0xf","to":"0xd","value":"0x7"},"result":{},"traceAddress":[0,0],"transactionHash":"0x2","type":"call"},
+	{"action":{"address":"0xb","refundAddress":"0xd","balance":"0x9"},"traceAddress":[1],"transactionHash":"0x2","type":"suicide"},
+	{"action":{"author":"0xminer","value":"0x1bc16d674ec80000"},"traceAddress":[],"type":"reward"}
+]`
+
+func TestInternalCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RequestBody
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch req.Method {
+		case DebugTraceBlockByNumber:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, callTracerResult)
+		case TraceBlock:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, parityTraceResult)
+		}
+	}))
+	defer server.Close()
+
+	client := NewETHClient(server.URL)
+	for name, api := range map[string]TraceAPI{"debug": TraceDebug, "parity": TraceParity} {
+		t.Run(name, func(t *testing.T) {
+			calls, err := client.InternalCalls(context.Background(), 16, api, []string{"0x1", "0x2"})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if len(calls) != 6 {
+				t.Fatalf("expected 6 internal calls, got %d", len(calls))
+			}
+
+			var transfers []InternalCall
+			for _, call := range calls {
+				if call.TransfersValue() {
+					transfers = append(transfers, *call)
+				}
+			}
+			expected := []InternalCall{
+				{TxHash: "0x1", Path: []int{0, 0}, Type: CallTypeCall, From: "0xb", To: "0xd", Value: "0x5"},
+				{TxHash: "0x2", Path: []int{1}, Type: CallTypeSelfDestruct, From: "0xb", To: "0xd", Value: "0x9"},
+			}
+			if !reflect.DeepEqual(transfers, expected) {
+				t.Errorf("unexpected transfers %+v", transfers)
+			}
+
+			// The transfer of a reverted call is reverted too.
+			if reverted := calls[4]; reverted.Value != "0x7" || reverted.Error == "" {
+				t.Errorf("expected reverted transfer, got %+v", reverted)
+			}
+		})
+	}
+}
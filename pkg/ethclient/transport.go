@@ -0,0 +1,124 @@
+package ethclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrResponseTooLarge is returned when a response exceeds the configured
+// maximum size.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// transport delivers an encoded JSON-RPC request, single or batch, and
+// returns the raw response.
+type transport interface {
+	roundTrip(ctx context.Context, body []byte) ([]byte, error)
+
+	close()
+}
+
+// httpConfig holds the HTTP settings shared by the transports of a client.
+type httpConfig struct {
+	client          *http.Client
+	headers         http.Header
+	auth            authFunc
+	maxResponseSize int64
+}
+
+// requestHeader returns the headers to send with a request, including
+// authentication.
+func (c httpConfig) requestHeader() (http.Header, error) {
+	header := c.headers.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	if c.auth != nil {
+		value, err := c.auth()
+		if err != nil {
+			return nil, fmt.Errorf("error authenticating request: %v", err)
+		}
+		header.Set("Authorization", value)
+	}
+	return header, nil
+}
+
+// httpTransport posts requests to a single HTTP endpoint.
+type httpTransport struct {
+	endpoint string
+	config   httpConfig
+}
+
+func newHTTPTransport(endpoint string, config httpConfig) *httpTransport {
+	return &httpTransport{endpoint: endpoint, config: config}
+}
+
+func (t *httpTransport) roundTrip(ctx context.Context, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	header, err := t.config.requestHeader()
+	if err != nil {
+		return nil, err
+	}
+	if extra, ok := ctx.Value(requestHeaderKey{}).(http.Header); ok {
+		for k, v := range extra {
+			header[k] = append(header[k], v...)
+		}
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.config.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", errTransport, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := readLimited(resp.Body, t.config.maxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       raw,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return raw, nil
+}
+
+func (t *httpTransport) close() {
+	t.config.client.CloseIdleConnections()
+}
+
+// readLimited reads r up to limit bytes, 0 means no limit.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		raw, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: error reading response body: %v", errTransport, err)
+		}
+		return raw, nil
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: error reading response body: %v", errTransport, err)
+	}
+	if int64(len(raw)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, limit)
+	}
+	return raw, nil
+}
@@ -0,0 +1,129 @@
+package ethclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case headers <- r.Header.Clone():
+		default:
+		}
+		// Consume the body so the server notices clients going away.
+		io.Copy(io.Discard, r.Body)
+
+		switch r.URL.Path {
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		case "/large":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":"0x%s"}`, strings.Repeat("1", 1024))
+		default:
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x10"}`)
+		}
+	}))
+	defer server.Close()
+
+	lastHeader := func() http.Header {
+		select {
+		case header := <-headers:
+			return header
+		default:
+			return nil
+		}
+	}
+
+	t.Run("Headers", func(t *testing.T) {
+		client := NewETHClient(server.URL, WithHeader("X-Api-Key", "secret"), WithBearerToken("token"))
+		if _, err := client.BlockNumber(context.Background()); err != nil {
+			t.Fatal(err.Error())
+		}
+		header := lastHeader()
+		if header.Get("X-Api-Key") != "secret" || header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected headers %v", header)
+		}
+		if header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %s", header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("BasicAuth", func(t *testing.T) {
+		client := NewETHClient(server.URL, WithBasicAuth("user", "pass"))
+		if _, err := client.BlockNumber(context.Background()); err != nil {
+			t.Fatal(err.Error())
+		}
+		if auth := lastHeader().Get("Authorization"); auth != "Basic dXNlcjpwYXNz" {
+			t.Errorf("unexpected authorization %s", auth)
+		}
+	})
+
+	t.Run("JWT", func(t *testing.T) {
+		secret := []byte("0123456789abcdef0123456789abcdef")
+		client := NewETHClient(server.URL, WithJWTSecret(secret))
+		if _, err := client.BlockNumber(context.Background()); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		token := strings.TrimPrefix(lastHeader().Get("Authorization"), "Bearer ")
+		parts := strings.Split(token, ".")
+		if len(parts) != 3 {
+			t.Fatalf("malformed token %s", token)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(parts[0] + "." + parts[1]))
+		if base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) != parts[2] {
+			t.Error("invalid token signature")
+		}
+		rawClaims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims struct {
+			IssuedAt int64 `json:"iat"`
+		}
+		json.Unmarshal(rawClaims, &claims)
+		if time.Since(time.Unix(claims.IssuedAt, 0)) > time.Minute {
+			t.Errorf("unexpected iat %d", claims.IssuedAt)
+		}
+	})
+
+	t.Run("ContextCancelled", func(t *testing.T) {
+		client := NewETHClient(server.URL + "/slow")
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := client.BlockNumber(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		client := NewETHClient(server.URL+"/slow", WithTimeout(50*time.Millisecond))
+		start := time.Now()
+		if _, err := client.BlockNumber(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Error("timeout not applied")
+		}
+	})
+
+	t.Run("MaxResponseSize", func(t *testing.T) {
+		client := NewETHClient(server.URL+"/large", WithMaxResponseSize(512))
+		if _, err := client.BlockNumber(context.Background()); !errors.Is(err, ErrResponseTooLarge) {
+			t.Errorf("expected ErrResponseTooLarge, got %v", err)
+		}
+	})
+}
@@ -1,5 +1,11 @@
 package ethclient
 
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
 type GetBlockByNumberResp struct {
 	Jsonrpc string    `json:"jsonrpc"`
 	Result  *ETHBlock `json:"result"`
@@ -31,10 +37,35 @@ type ETHBlock struct {
 	Transactions          []*ETHTransaction `json:"transactions"`
 	TransactionsRoot      string            `json:"transactionsRoot"`
 	Uncles                []interface{}     `json:"uncles"`
-	Withdrawals           []*ETHWithdrawal  `json:"result"`
+	Withdrawals           []*ETHWithdrawal  `json:"withdrawals"`
 	WithdrawalsRoot       string            `json:"withdrawalsRoot"`
 }
 
+// Header is a block header, as delivered by newHeads subscriptions.
+type Header struct {
+	BaseFeePerGas         string `json:"baseFeePerGas"`
+	BlobGasUsed           string `json:"blobGasUsed"`
+	Difficulty            string `json:"difficulty"`
+	ExcessBlobGas         string `json:"excessBlobGas"`
+	ExtraData             string `json:"extraData"`
+	GasLimit              string `json:"gasLimit"`
+	GasUsed               string `json:"gasUsed"`
+	Hash                  string `json:"hash"`
+	LogsBloom             string `json:"logsBloom"`
+	Miner                 string `json:"miner"`
+	MixHash               string `json:"mixHash"`
+	Nonce                 string `json:"nonce"`
+	Number                string `json:"number"`
+	ParentBeaconBlockRoot string `json:"parentBeaconBlockRoot"`
+	ParentHash            string `json:"parentHash"`
+	ReceiptsRoot          string `json:"receiptsRoot"`
+	Sha3Uncles            string `json:"sha3Uncles"`
+	StateRoot             string `json:"stateRoot"`
+	Timestamp             string `json:"timestamp"`
+	TransactionsRoot      string `json:"transactionsRoot"`
+	WithdrawalsRoot       string `json:"withdrawalsRoot"`
+}
+
 type ETHTransaction struct {
 	BlockHash            string `json:"blockHash"`
 	BlockNumber          string `json:"blockNumber"`
@@ -60,19 +91,103 @@ type ETHTransaction struct {
 	S       string `json:"s"`
 }
 
+// Receipt is the execution outcome of a transaction.
+type Receipt struct {
+	BlockHash         string `json:"blockHash"`
+	BlockNumber       string `json:"blockNumber"`
+	ContractAddress   string `json:"contractAddress"`
+	CumulativeGasUsed string `json:"cumulativeGasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	From              string `json:"from"`
+	GasUsed           string `json:"gasUsed"`
+	Logs              []*Log `json:"logs"`
+	LogsBloom         string `json:"logsBloom"`
+	Status            string `json:"status"`
+	To                string `json:"to"`
+	TransactionHash   string `json:"transactionHash"`
+	TransactionIndex  string `json:"transactionIndex"`
+	Type              string `json:"type"`
+}
+
+// Log is an event emitted by a contract.
+type Log struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+}
+
+// ETHWithdrawal is a withdrawal of staked ether from the beacon chain to
+// an execution layer address, included in a block since Shanghai.
 type ETHWithdrawal struct {
+	Index          uint64
+	ValidatorIndex uint64
+	Address        string
+	// Amount is in gwei.
+	Amount uint64
+}
+
+// rpcWithdrawal is a withdrawal as encoded by the node, with hex
+// quantities.
+type rpcWithdrawal struct {
 	Index          string `json:"index"`
 	ValidatorIndex string `json:"validatorIndex"`
 	Address        string `json:"address"`
 	Amount         string `json:"amount"`
 }
 
+func (w *ETHWithdrawal) UnmarshalJSON(data []byte) error {
+	var raw rpcWithdrawal
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var err error
+	if w.Index, err = parseHexUint64(raw.Index); err != nil {
+		return fmt.Errorf("invalid withdrawal index: %v", err)
+	}
+	if w.ValidatorIndex, err = parseHexUint64(raw.ValidatorIndex); err != nil {
+		return fmt.Errorf("invalid withdrawal validator index: %v", err)
+	}
+	if w.Amount, err = parseHexUint64(raw.Amount); err != nil {
+		return fmt.Errorf("invalid withdrawal amount: %v", err)
+	}
+	w.Address = raw.Address
+	return nil
+}
+
+func (w ETHWithdrawal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rpcWithdrawal{
+		Index:          fmt.Sprintf("0x%x", w.Index),
+		ValidatorIndex: fmt.Sprintf("0x%x", w.ValidatorIndex),
+		Address:        w.Address,
+		Amount:         fmt.Sprintf("0x%x", w.Amount),
+	})
+}
+
+// AmountWei returns the amount of the withdrawal in wei.
+func (w *ETHWithdrawal) AmountWei() *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(w.Amount), big.NewInt(1e9))
+}
+
 type GetBlockNumberResp struct {
 	Jsonrpc string `json:"jsonrpc"`
 	ID      int    `json:"id"`
 	Result  string `json:"result"`
 }
 
+type ResponseBody struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *RPCError       `json:"error"`
+}
+
 type RequestBody struct {
 	Jsonrpc string      `json:"jsonrpc"`
 	ID      int         `json:"id"`
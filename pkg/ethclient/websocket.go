@@ -0,0 +1,370 @@
+package ethclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes, see RFC 6455 section 5.2.
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xa
+)
+
+const (
+	// wsAcceptGUID is appended to the handshake key to compute the accept key.
+	wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsCloseNormal = 1000
+
+	// wsMaxMessageSize bounds a reassembled message, full blocks of busy
+	// chains run in the megabytes.
+	wsMaxMessageSize = 64 << 20
+
+	// wsReadTimeout drops connections not delivering any frame, not even
+	// the answer to a ping, for two ping intervals.
+	wsReadTimeout = 2 * streamPingInterval
+)
+
+var errWSClosed = errors.New("websocket closed")
+
+// wsConn is a WebSocket connection speaking the RFC 6455 framing. Clients
+// mask the frames they send, servers do not.
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	client bool
+
+	// readTimeout, if set, is the maximum time to wait for the next frame.
+	readTimeout time.Duration
+
+	writeLock sync.Mutex
+}
+
+func newWSConn(conn net.Conn, reader *bufio.Reader, client bool) *wsConn {
+	if reader == nil {
+		reader = bufio.NewReader(conn)
+	}
+	return &wsConn{conn: conn, reader: reader, client: client}
+}
+
+// newWSTransport returns a transport multiplexing requests and
+// subscriptions over a WebSocket connection to endpoint.
+func newWSTransport(endpoint string, config httpConfig) *streamTransport {
+	return newStreamTransport("websocket", endpoint, func(ctx context.Context) (messageConn, error) {
+		// Headers are built for every dial so JWT tokens are fresh.
+		header, err := config.requestHeader()
+		if err != nil {
+			return nil, err
+		}
+		conn, err := dialWebSocket(ctx, endpoint, header)
+		if err != nil {
+			return nil, err
+		}
+		conn.readTimeout = wsReadTimeout
+		return conn, nil
+	})
+}
+
+// dialWebSocket opens a client connection to a ws:// or wss:// endpoint.
+func dialWebSocket(ctx context.Context, endpoint string, header http.Header) (*wsConn, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid websocket endpoint: %v", err)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		switch u.Scheme {
+		case "ws":
+			host = net.JoinHostPort(u.Hostname(), "80")
+		case "wss":
+			host = net.JoinHostPort(u.Hostname(), "443")
+		default:
+			return nil, fmt.Errorf("unsupported websocket scheme %q", u.Scheme)
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	// Bound the handshake by the context deadline.
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	ws, err := wsHandshake(conn, u, header)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return ws, nil
+}
+
+func wsHandshake(conn net.Conn, u *url.URL, header http.Header) (*wsConn, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Scheme: "http", Host: u.Host, Path: u.Path, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		req.SetBasicAuth(u.User.Username(), password)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") ||
+		!headerContainsToken(resp.Header, "Connection", "upgrade") {
+		return nil, errors.New("websocket handshake: missing upgrade headers")
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		return nil, errors.New("websocket handshake: invalid accept key")
+	}
+	return newWSConn(conn, reader, true), nil
+}
+
+func wsAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next text or binary message, answering control
+// frames in between. Fragmented messages are reassembled.
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			c.writeFrame(wsOpClose, payload)
+			return nil, errWSClosed
+		case wsOpText, wsOpBinary:
+			if started {
+				return nil, errors.New("websocket: new message before end of fragmented message")
+			}
+			started = true
+			message = payload
+		case wsOpContinuation:
+			if !started {
+				return nil, errors.New("websocket: continuation frame without message")
+			}
+			if len(message)+len(payload) > wsMaxMessageSize {
+				return nil, errors.New("websocket: message too large")
+			}
+			message = append(message, payload...)
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %d", op)
+		}
+
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// WriteMessage sends data as a single text frame.
+func (c *wsConn) WriteMessage(data []byte) error {
+	return c.writeFrame(wsOpText, data)
+}
+
+// Ping sends a ping control frame.
+func (c *wsConn) Ping() error {
+	return c.writeFrame(wsOpPing, nil)
+}
+
+// Close sends a normal close frame and closes the underlying connection.
+func (c *wsConn) Close() error {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, wsCloseNormal)
+	c.writeFrame(wsOpClose, payload)
+	return c.conn.Close()
+}
+
+func (c *wsConn) abort() {
+	c.conn.Close()
+}
+
+func (c *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	if c.readTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, errors.New("websocket: reserved bits set")
+	}
+	op = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	if masked == c.client {
+		// Servers must not mask frames, clients must.
+		return false, 0, nil, errors.New("websocket: invalid frame masking")
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= wsOpClose && (length > 125 || !fin) {
+		return false, 0, nil, errors.New("websocket: invalid control frame")
+	}
+	if length > wsMaxMessageSize {
+		return false, 0, nil, errors.New("websocket: frame too large")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return fin, op, payload, nil
+}
+
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|op)
+
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, maskBit|126, byte(length>>8), byte(length))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(length))
+		frame = append(frame, maskBit|127)
+		frame = append(frame, ext[:]...)
+	}
+
+	if !c.client {
+		frame = append(frame, payload...)
+	} else {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
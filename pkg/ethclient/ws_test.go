@@ -0,0 +1,267 @@
+package ethclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// wsNode is a minimal WebSocket JSON-RPC node answering eth_blockNumber
+// and eth_subscribe, and pushing heads on demand.
+type wsNode struct {
+	server *httptest.Server
+
+	lock          sync.Mutex
+	conns         []*wsConn
+	subscriptions int
+	subscribed    chan string
+}
+
+func newWSNode() *wsNode {
+	n := &wsNode{subscribed: make(chan string, 16)}
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		n.lock.Lock()
+		n.conns = append(n.conns, conn)
+		n.lock.Unlock()
+		n.serve(conn)
+	}))
+	return n
+}
+
+func (n *wsNode) url() string {
+	return "ws" + strings.TrimPrefix(n.server.URL, "http")
+}
+
+func (n *wsNode) serve(conn *wsConn) {
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req RequestBody
+		if err := json.Unmarshal(message, &req); err != nil {
+			return
+		}
+
+		switch req.Method {
+		case GetBlockbusterMethod:
+			conn.WriteMessage([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":"0x10"}`, req.ID)))
+		case "eth_subscribe":
+			n.lock.Lock()
+			n.subscriptions++
+			id := fmt.Sprintf("0xsub%d", n.subscriptions)
+			n.lock.Unlock()
+			conn.WriteMessage([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":"%s"}`, req.ID, id)))
+			n.subscribed <- id
+		case "eth_unsubscribe":
+			conn.WriteMessage([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":true}`, req.ID)))
+		}
+	}
+}
+
+// pushHead notifies the latest connection of a new head.
+func (n *wsNode) pushHead(subID string, number int) {
+	n.lock.Lock()
+	conn := n.conns[len(n.conns)-1]
+	n.lock.Unlock()
+	conn.WriteMessage([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"%s","result":{"number":"0x%x","hash":"0x1"}}}`, subID, number)))
+}
+
+// dropConnections closes every connection without a close handshake.
+func (n *wsNode) dropConnections() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, conn := range n.conns {
+		conn.conn.Close()
+	}
+}
+
+func (n *wsNode) close() {
+	n.dropConnections()
+	n.server.Close()
+}
+
+func TestWebSocketFraming(t *testing.T) {
+	clientSide, serverSide := net.Pipe()
+	client := newWSConn(clientSide, nil, true)
+	server := newWSConn(serverSide, nil, false)
+	defer client.conn.Close()
+	defer server.conn.Close()
+
+	large := bytes.Repeat([]byte("a"), 70000)
+	go func() {
+		// A ping and a message fragmented in three frames, the client
+		// must answer the ping and reassemble the message.
+		server.writeFrame(wsOpPing, []byte("ping"))
+		server.writeRaw(wsOpText, false, []byte("hello "))
+		server.writeRaw(wsOpContinuation, false, []byte("fragmented "))
+		server.writeRaw(wsOpContinuation, true, []byte("world"))
+		server.WriteMessage(large)
+	}()
+
+	pong := make(chan []byte, 1)
+	go func() {
+		_, op, payload, err := server.readFrame()
+		if err == nil && op == wsOpPong {
+			pong <- payload
+		}
+	}()
+
+	message, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(message) != "hello fragmented world" {
+		t.Errorf("unexpected message %q", message)
+	}
+	select {
+	case payload := <-pong:
+		if string(payload) != "ping" {
+			t.Errorf("unexpected pong payload %q", payload)
+		}
+	case <-time.After(time.Second):
+		t.Error("no pong received")
+	}
+
+	message, err = client.ReadMessage()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !bytes.Equal(message, large) {
+		t.Errorf("large message corrupted, got %d bytes", len(message))
+	}
+}
+
+// writeRaw writes a single unmasked frame with the given FIN bit.
+func (c *wsConn) writeRaw(op byte, fin bool, payload []byte) error {
+	first := op
+	if fin {
+		first |= 0x80
+	}
+	_, err := c.conn.Write(append([]byte{first, byte(len(payload))}, payload...))
+	return err
+}
+
+func TestWebSocketClient(t *testing.T) {
+	node := newWSNode()
+	defer node.close()
+
+	client := NewETHClient(node.url())
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	blockNumber, err := client.BlockNumber(ctx)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if blockNumber != 16 {
+		t.Errorf("expected block 16, got %d", blockNumber)
+	}
+
+	heads := make(chan *Header, 1)
+	sub, err := client.SubscribeNewHeads(ctx, heads)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer sub.Unsubscribe()
+
+	subID := <-node.subscribed
+	node.pushHead(subID, 17)
+	if head := receiveHead(t, heads); head.Number != "0x11" {
+		t.Errorf("unexpected head %s", head.Number)
+	}
+
+	// Drop the connection: the subscriber is told, then the subscription
+	// comes back on a new connection.
+	node.dropConnections()
+	select {
+	case err := <-sub.Err():
+		if !errors.Is(err, ErrConnectionLost) {
+			t.Errorf("expected ErrConnectionLost, got %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("no connection lost error")
+	}
+
+	var newID string
+	select {
+	case newID = <-node.subscribed:
+	case <-ctx.Done():
+		t.Fatal("subscription not re-established")
+	}
+	// The new id is registered before the response is handed back, so
+	// wait for the resubscription round trip with a request.
+	if _, err := client.BlockNumber(ctx); err != nil {
+		t.Fatal(err.Error())
+	}
+	node.pushHead(newID, 18)
+	if head := receiveHead(t, heads); head.Number != "0x12" {
+		t.Errorf("unexpected head %s", head.Number)
+	}
+	if sub.ID() != newID {
+		t.Errorf("expected subscription id %s, got %s", newID, sub.ID())
+	}
+}
+
+func TestSubscribeOverHTTP(t *testing.T) {
+	client := NewETHClient("http://127.0.0.1:0")
+	if _, err := client.SubscribeNewHeads(context.Background(), make(chan *Header)); !errors.Is(err, ErrNotificationsUnsupported) {
+		t.Errorf("expected ErrNotificationsUnsupported, got %v", err)
+	}
+}
+
+func receiveHead(t *testing.T, heads <-chan *Header) *Header {
+	t.Helper()
+	select {
+	case head := <-heads:
+		return head
+	case <-time.After(5 * time.Second):
+		t.Fatal("no head received")
+		return nil
+	}
+}
+
+// upgradeWebSocket answers a client handshake and takes over the
+// connection of the request. It is the server side of dialWebSocket.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		!headerContainsToken(r.Header, "Connection", "upgrade") {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, errors.New("not a websocket handshake")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+		return nil, errors.New("unsupported websocket handshake")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", wsAcceptKey(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newWSConn(conn, rw.Reader, false), nil
+}
@@ -0,0 +1,319 @@
+package ethtest
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/352174109/trustwallet-homework/pkg/abi"
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+)
+
+const (
+	// blockTime is the number of seconds between two mined blocks.
+	blockTime = 12
+	// genesisTime is the timestamp of the genesis block, the merge.
+	genesisTime = 1663224162
+
+	baseFee     = 1000000000
+	priorityFee = 1000000000
+	transferGas = 21000
+	gasLimit    = 30000000
+
+	zeroAddress = "0x0000000000000000000000000000000000000000"
+	zeroHash    = "0x0000000000000000000000000000000000000000000000000000000000000000"
+	// emptyRoot is the root of an empty trie.
+	emptyRoot = "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"
+	// emptyUncles is the hash of an empty uncle list.
+	emptyUncles = "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347"
+)
+
+// emptyBloom is the bloom filter of a block without logs.
+var emptyBloom = "0x" + strings.Repeat("0", 512)
+
+// Tx is a transaction to include in a mined block.
+type Tx struct {
+	From  string
+	To    string
+	Value *big.Int
+	// Input is the hex encoded call data, empty for plain transfers.
+	Input string
+	// Gas is the gas used by the transaction, 21000 if 0.
+	Gas uint64
+	// Failed makes the receipt status 0x0, the logs are dropped.
+	Failed bool
+	Logs   []Log
+	// ChainID is the chain the transaction is signed for, the one of the
+	// node if 0.
+	ChainID uint64
+}
+
+// Withdrawal is a beacon chain withdrawal to include in a mined block.
+type Withdrawal struct {
+	ValidatorIndex uint64
+	Address        string
+	// Amount is in gwei.
+	Amount uint64
+}
+
+// Log is an event emitted by a transaction.
+type Log struct {
+	Address string
+	Topics  []string
+	Data    string
+}
+
+// Block is a mined block, with the transactions and receipts served by
+// the node.
+type Block struct {
+	Number       int
+	Hash         string
+	ParentHash   string
+	Timestamp    uint64
+	Transactions []*ethclient.ETHTransaction
+	Receipts     []*ethclient.Receipt
+	Withdrawals  []*ethclient.ETHWithdrawal
+}
+
+// header returns the header of the block as served by the node.
+func (b *Block) header() ethclient.Header {
+	var gasUsed uint64
+	if n := len(b.Receipts); n > 0 {
+		gasUsed = mustParseUint(b.Receipts[n-1].CumulativeGasUsed)
+	}
+	return ethclient.Header{
+		BaseFeePerGas:    hexUint(baseFee),
+		Difficulty:       "0x0",
+		ExtraData:        "0x",
+		GasLimit:         hexUint(gasLimit),
+		GasUsed:          hexUint(gasUsed),
+		Hash:             b.Hash,
+		LogsBloom:        emptyBloom,
+		Miner:            zeroAddress,
+		MixHash:          zeroHash,
+		Nonce:            "0x0000000000000000",
+		Number:           hexUint(uint64(b.Number)),
+		ParentHash:       b.ParentHash,
+		ReceiptsRoot:     emptyRoot,
+		Sha3Uncles:       emptyUncles,
+		StateRoot:        emptyRoot,
+		Timestamp:        hexUint(b.Timestamp),
+		TransactionsRoot: emptyRoot,
+		WithdrawalsRoot:  emptyRoot,
+	}
+}
+
+// rpcBlock is a block as encoded by the node, transactions being either
+// objects or hashes.
+type rpcBlock struct {
+	ethclient.Header
+	Size         string                     `json:"size"`
+	Transactions interface{}                `json:"transactions"`
+	Uncles       []string                   `json:"uncles"`
+	Withdrawals  []*ethclient.ETHWithdrawal `json:"withdrawals"`
+}
+
+func (b *Block) encode(fullTx bool) *rpcBlock {
+	block := &rpcBlock{
+		Header:      b.header(),
+		Size:        hexUint(uint64(1000 + 200*len(b.Transactions))),
+		Uncles:      []string{},
+		Withdrawals: b.Withdrawals,
+	}
+	if block.Withdrawals == nil {
+		block.Withdrawals = []*ethclient.ETHWithdrawal{}
+	}
+	if fullTx {
+		block.Transactions = b.Transactions
+	} else {
+		hashes := make([]string, len(b.Transactions))
+		for i, tx := range b.Transactions {
+			hashes[i] = tx.Hash
+		}
+		block.Transactions = hashes
+	}
+	return block
+}
+
+// mine appends a block with the given transactions and withdrawals to the
+// canonical chain. The caller holds the lock.
+func (n *Node) mine(txs []Tx, withdrawals []Withdrawal) *Block {
+	parent := n.chain[len(n.chain)-1]
+	block := &Block{
+		Number:     parent.Number + 1,
+		ParentHash: parent.Hash,
+		Timestamp:  parent.Timestamp + blockTime,
+	}
+	block.Hash = hash("block", parent.Hash, block.Number, n.forks)
+
+	var cumulativeGas uint64
+	for i, t := range txs {
+		from := strings.ToLower(t.From)
+		nonce := n.nonceAt(from, parent.Number)
+		for _, mined := range block.Transactions {
+			if mined.From == from {
+				nonce++
+			}
+		}
+
+		tx := n.transaction(t, nonce)
+		tx.BlockHash = block.Hash
+		tx.BlockNumber = hexUint(uint64(block.Number))
+		tx.TransactionIndex = hexUint(uint64(i))
+		block.Transactions = append(block.Transactions, tx)
+		gas := mustParseUint(tx.Gas)
+		to := tx.To
+
+		cumulativeGas += gas
+		receipt := &ethclient.Receipt{
+			BlockHash:         block.Hash,
+			BlockNumber:       tx.BlockNumber,
+			CumulativeGasUsed: hexUint(cumulativeGas),
+			EffectiveGasPrice: tx.GasPrice,
+			From:              from,
+			GasUsed:           hexUint(gas),
+			Logs:              []*ethclient.Log{},
+			LogsBloom:         emptyBloom,
+			Status:            "0x1",
+			To:                to,
+			TransactionHash:   tx.Hash,
+			TransactionIndex:  tx.TransactionIndex,
+			Type:              tx.Type,
+		}
+		if t.Failed {
+			receipt.Status = "0x0"
+		} else {
+			first := n.logIndex(block)
+			for j, l := range t.Logs {
+				data := l.Data
+				if data == "" {
+					data = "0x"
+				}
+				receipt.Logs = append(receipt.Logs, &ethclient.Log{
+					Address:          strings.ToLower(l.Address),
+					Topics:           l.Topics,
+					Data:             data,
+					BlockNumber:      tx.BlockNumber,
+					BlockHash:        block.Hash,
+					TransactionHash:  tx.Hash,
+					TransactionIndex: tx.TransactionIndex,
+					LogIndex:         hexUint(uint64(first + j)),
+				})
+			}
+		}
+		block.Receipts = append(block.Receipts, receipt)
+	}
+
+	for _, w := range withdrawals {
+		block.Withdrawals = append(block.Withdrawals, &ethclient.ETHWithdrawal{
+			Index:          n.withdrawals,
+			ValidatorIndex: w.ValidatorIndex,
+			Address:        strings.ToLower(w.Address),
+			Amount:         w.Amount,
+		})
+		n.withdrawals++
+	}
+
+	n.chain = append(n.chain, block)
+	n.blocks[block.Hash] = block
+	for _, tx := range block.Transactions {
+		n.txBlocks[tx.Hash] = block
+	}
+	n.evictMined()
+	return block
+}
+
+// transaction returns the transaction t sent with the given nonce, not
+// part of a block.
+func (n *Node) transaction(t Tx, nonce uint64) *ethclient.ETHTransaction {
+	from, to := strings.ToLower(t.From), strings.ToLower(t.To)
+	value := t.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	input := t.Input
+	if input == "" {
+		input = "0x"
+	}
+	gas := t.Gas
+	if gas == 0 {
+		gas = transferGas
+	}
+	chainID := t.ChainID
+	if chainID == 0 {
+		chainID = n.chainID
+	}
+
+	return &ethclient.ETHTransaction{
+		From:                 from,
+		Gas:                  hexUint(gas),
+		GasPrice:             hexUint(baseFee + priorityFee),
+		MaxPriorityFeePerGas: hexUint(priorityFee),
+		MaxFeePerGas:         hexUint(2*baseFee + priorityFee),
+		Hash:                 hash("tx", chainID, from, nonce, to, value, input),
+		Input:                input,
+		Nonce:                hexUint(nonce),
+		To:                   to,
+		Value:                "0x" + value.Text(16),
+		Type:                 "0x2",
+		ChainId:              hexUint(chainID),
+		V:                    "0x0",
+		R:                    "0x1",
+		S:                    "0x1",
+	}
+}
+
+// logIndex returns the index of the next log of the block.
+func (n *Node) logIndex(block *Block) int {
+	count := 0
+	for _, receipt := range block.Receipts {
+		count += len(receipt.Logs)
+	}
+	return count
+}
+
+// nonceAt returns the number of transactions sent by address in the
+// canonical chain up to the given block.
+func (n *Node) nonceAt(address string, number int) uint64 {
+	var nonce uint64
+	for _, block := range n.chain[:number+1] {
+		for _, tx := range block.Transactions {
+			if tx.From == address {
+				nonce++
+			}
+		}
+	}
+	return nonce
+}
+
+// canonical reports whether the block is part of the canonical chain.
+func (n *Node) canonical(block *Block) bool {
+	return block.Number < len(n.chain) && n.chain[block.Number] == block
+}
+
+// head returns the block at the given depth below the head, the genesis
+// block if the chain is not that long.
+func (n *Node) head(depth int) *Block {
+	number := len(n.chain) - 1 - depth
+	if number < 0 {
+		number = 0
+	}
+	return n.chain[number]
+}
+
+// hash returns a deterministic hash of the given values.
+func hash(values ...interface{}) string {
+	return fmt.Sprintf("0x%x", abi.Keccak256([]byte(fmt.Sprint(values...))))
+}
+
+func hexUint(v uint64) string {
+	return fmt.Sprintf("0x%x", v)
+}
+
+func mustParseUint(s string) uint64 {
+	v, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok {
+		panic("invalid hex number " + s)
+	}
+	return v.Uint64()
+}
@@ -0,0 +1,155 @@
+package ethtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+)
+
+// pendingTx is a transaction waiting in the mempool.
+type pendingTx struct {
+	tx    Tx
+	rpc   *ethclient.ETHTransaction
+	nonce uint64
+}
+
+// Send adds the transactions to the mempool, where they are served by hash
+// and announced to the pending transaction filters until they are mined
+// with MinePending, replaced or dropped.
+func (n *Node) Send(txs ...Tx) []*ethclient.ETHTransaction {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	sent := make([]*ethclient.ETHTransaction, len(txs))
+	for i, t := range txs {
+		from := strings.ToLower(t.From)
+		nonce := n.nonceAt(from, n.head(0).Number)
+		for _, pending := range n.pool {
+			if pending.rpc.From == from {
+				nonce++
+			}
+		}
+		sent[i] = n.addPending(t, nonce)
+	}
+	return sent
+}
+
+// Replace replaces the pending transaction with the given hash by tx, sent
+// with the same nonce, e.g. to speed it up or to cancel it.
+func (n *Node) Replace(hash string, tx Tx) *ethclient.ETHTransaction {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	for i, pending := range n.pool {
+		if pending.rpc.Hash == hash {
+			n.pool = append(n.pool[:i], n.pool[i+1:]...)
+			return n.addPending(tx, pending.nonce)
+		}
+	}
+	return nil
+}
+
+// Drop removes the transaction from the mempool, like a node evicting it.
+func (n *Node) Drop(hash string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	for i, pending := range n.pool {
+		if pending.rpc.Hash == hash {
+			n.pool = append(n.pool[:i], n.pool[i+1:]...)
+			return
+		}
+	}
+}
+
+// MinePending mines a block with the transactions of the mempool, in the
+// order they were sent.
+func (n *Node) MinePending() *Block {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	txs := make([]Tx, len(n.pool))
+	for i, pending := range n.pool {
+		txs[i] = pending.tx
+	}
+	n.pool = nil
+	return n.mine(txs, nil)
+}
+
+// addPending adds tx sent with the given nonce to the mempool. The caller
+// holds the lock.
+func (n *Node) addPending(t Tx, nonce uint64) *ethclient.ETHTransaction {
+	tx := n.transaction(t, nonce)
+	n.pool = append(n.pool, &pendingTx{tx: t, rpc: tx, nonce: nonce})
+	for id, hashes := range n.filters {
+		n.filters[id] = append(hashes, tx.Hash)
+	}
+	return tx
+}
+
+// evictMined removes from the mempool the transactions whose nonce was
+// used by a mined transaction. The caller holds the lock.
+func (n *Node) evictMined() {
+	pool := n.pool[:0]
+	for _, pending := range n.pool {
+		if pending.nonce >= n.nonceAt(pending.rpc.From, n.head(0).Number) {
+			pool = append(pool, pending)
+		}
+	}
+	n.pool = pool
+}
+
+// pending returns the pending transaction with the given hash, nil if
+// there is none. The caller holds the lock.
+func (n *Node) pending(hash string) *ethclient.ETHTransaction {
+	for _, pending := range n.pool {
+		if pending.rpc.Hash == hash {
+			return pending.rpc
+		}
+	}
+	return nil
+}
+
+func (n *Node) newPendingTransactionFilter([]json.RawMessage) (interface{}, error) {
+	n.filterCount++
+	id := hexUint(uint64(n.filterCount))
+	n.filters[id] = []string{}
+	return id, nil
+}
+
+func (n *Node) getFilterChanges(params []json.RawMessage) (interface{}, error) {
+	id, err := filterParam(params)
+	if err != nil {
+		return nil, err
+	}
+	hashes, ok := n.filters[id]
+	if !ok {
+		return nil, &ethclient.RPCError{Code: ethclient.CodeServerError, Message: "filter not found"}
+	}
+	n.filters[id] = []string{}
+	return hashes, nil
+}
+
+func (n *Node) uninstallFilter(params []json.RawMessage) (interface{}, error) {
+	id, err := filterParam(params)
+	if err != nil {
+		return nil, err
+	}
+	_, ok := n.filters[id]
+	delete(n.filters, id)
+	return ok, nil
+}
+
+func filterParam(params []json.RawMessage) (string, error) {
+	if len(params) != 1 {
+		return "", errors.New("expected filter id param")
+	}
+	var id string
+	if err := json.Unmarshal(params[0], &id); err != nil {
+		return "", fmt.Errorf("invalid filter id: %v", err)
+	}
+	return id, nil
+}
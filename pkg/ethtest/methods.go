@@ -0,0 +1,397 @@
+package ethtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+)
+
+// builtin returns the handler of a method implemented by the node.
+func (n *Node) builtin(method string) (MethodHandler, bool) {
+	var handler func(params []json.RawMessage) (interface{}, error)
+	switch method {
+	case ethclient.GetChainID:
+		handler = func([]json.RawMessage) (interface{}, error) { return hexUint(n.chainID), nil }
+	case ethclient.NetVersion:
+		handler = func([]json.RawMessage) (interface{}, error) { return strconv.FormatUint(n.chainID, 10), nil }
+	case ethclient.GetBlockbusterMethod:
+		handler = func([]json.RawMessage) (interface{}, error) { return hexUint(uint64(n.head(0).Number)), nil }
+	case ethclient.GetBlockByNumber, ethclient.GetBlockByHash:
+		handler = n.getBlock
+	case ethclient.GetBlockTransactionCountByNumber, ethclient.GetBlockTransactionCountByHash:
+		handler = n.getBlockTransactionCount
+	case ethclient.GetUncleByBlockHashAndIndex:
+		handler = n.getUncle
+	case ethclient.GetUncleCountByBlockHash, ethclient.GetUncleCountByBlockNumber:
+		handler = n.getUncleCount
+	case ethclient.GetTransactionByBlockHashAndIndex, ethclient.GetTransactionByBlockNumberAndIndex:
+		handler = n.getTransactionInBlock
+	case ethclient.Syncing:
+		handler = func([]json.RawMessage) (interface{}, error) { return false, nil }
+	case ethclient.NetPeerCount:
+		handler = func([]json.RawMessage) (interface{}, error) { return "0x0", nil }
+	case ethclient.NetListening:
+		handler = func([]json.RawMessage) (interface{}, error) { return false, nil }
+	case ethclient.GetTransactionByHash:
+		handler = n.getTransaction
+	case ethclient.GetTransactionReceipt:
+		handler = n.getReceipt
+	case ethclient.GetBlockReceipts:
+		handler = n.getBlockReceipts
+	case ethclient.GetLogs:
+		handler = n.getLogs
+	case ethclient.GetBalance:
+		handler = n.getBalance
+	case ethclient.GetTransactionCount:
+		handler = n.getTransactionCount
+	case ethclient.NewPendingTransactionFilter:
+		handler = n.newPendingTransactionFilter
+	case ethclient.GetFilterChanges:
+		handler = n.getFilterChanges
+	case ethclient.UninstallFilter:
+		handler = n.uninstallFilter
+	default:
+		return nil, false
+	}
+
+	return func(params []json.RawMessage) (interface{}, error) {
+		n.lock.Lock()
+		defer n.lock.Unlock()
+		return handler(params)
+	}, true
+}
+
+func (n *Node) getBlock(params []json.RawMessage) (interface{}, error) {
+	if len(params) != 2 {
+		return nil, errors.New("expected block and fullTx params")
+	}
+	block, err := n.blockParam(params[0])
+	if err != nil || block == nil {
+		return nil, err
+	}
+	var fullTx bool
+	if err := json.Unmarshal(params[1], &fullTx); err != nil {
+		return nil, fmt.Errorf("invalid fullTx param: %v", err)
+	}
+	return block.encode(fullTx), nil
+}
+
+func (n *Node) getBlockTransactionCount(params []json.RawMessage) (interface{}, error) {
+	if len(params) != 1 {
+		return nil, errors.New("expected block param")
+	}
+	block, err := n.blockParam(params[0])
+	if err != nil || block == nil {
+		return nil, err
+	}
+	return hexUint(uint64(len(block.Transactions))), nil
+}
+
+// getUncle serves no uncle, blocks have none since the merge.
+func (n *Node) getUncle(params []json.RawMessage) (interface{}, error) {
+	if len(params) != 2 {
+		return nil, errors.New("expected block hash and index params")
+	}
+	if _, err := n.blockParam(params[0]); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (n *Node) getUncleCount(params []json.RawMessage) (interface{}, error) {
+	if len(params) != 1 {
+		return nil, errors.New("expected block param")
+	}
+	block, err := n.blockParam(params[0])
+	if err != nil || block == nil {
+		return nil, err
+	}
+	return "0x0", nil
+}
+
+func (n *Node) getTransactionInBlock(params []json.RawMessage) (interface{}, error) {
+	if len(params) != 2 {
+		return nil, errors.New("expected block and index params")
+	}
+	block, err := n.blockParam(params[0])
+	if err != nil || block == nil {
+		return nil, err
+	}
+	var index string
+	if err := json.Unmarshal(params[1], &index); err != nil {
+		return nil, fmt.Errorf("invalid index param: %v", err)
+	}
+	i, err := strconv.ParseUint(strings.TrimPrefix(index, "0x"), 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index param: %v", err)
+	}
+	if i >= uint64(len(block.Transactions)) {
+		return nil, nil
+	}
+	return block.Transactions[i], nil
+}
+
+func (n *Node) getTransaction(params []json.RawMessage) (interface{}, error) {
+	block, index, err := n.txParam(params)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		var hash string
+		json.Unmarshal(params[0], &hash)
+		if tx := n.pending(strings.ToLower(hash)); tx != nil {
+			return tx, nil
+		}
+		return nil, nil
+	}
+	return block.Transactions[index], nil
+}
+
+func (n *Node) getReceipt(params []json.RawMessage) (interface{}, error) {
+	block, index, err := n.txParam(params)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	return block.Receipts[index], nil
+}
+
+func (n *Node) getBlockReceipts(params []json.RawMessage) (interface{}, error) {
+	if len(params) != 1 {
+		return nil, errors.New("expected block param")
+	}
+	block, err := n.blockParam(params[0])
+	if err != nil || block == nil {
+		return nil, err
+	}
+	receipts := block.Receipts
+	if receipts == nil {
+		receipts = []*ethclient.Receipt{}
+	}
+	return receipts, nil
+}
+
+func (n *Node) getBalance(params []json.RawMessage) (interface{}, error) {
+	address, _, err := n.accountParams(params)
+	if err != nil {
+		return nil, err
+	}
+	balance, ok := n.balances[address]
+	if !ok {
+		return "0x0", nil
+	}
+	return "0x" + balance.Text(16), nil
+}
+
+func (n *Node) getTransactionCount(params []json.RawMessage) (interface{}, error) {
+	address, block, err := n.accountParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return hexUint(n.nonceAt(address, block.Number)), nil
+}
+
+// logFilter is the filter object of eth_getLogs.
+type logFilter struct {
+	FromBlock json.RawMessage   `json:"fromBlock"`
+	ToBlock   json.RawMessage   `json:"toBlock"`
+	BlockHash string            `json:"blockHash"`
+	Address   json.RawMessage   `json:"address"`
+	Topics    []json.RawMessage `json:"topics"`
+}
+
+func (n *Node) getLogs(params []json.RawMessage) (interface{}, error) {
+	if len(params) != 1 {
+		return nil, errors.New("expected filter param")
+	}
+	var filter logFilter
+	if err := json.Unmarshal(params[0], &filter); err != nil {
+		return nil, fmt.Errorf("invalid filter: %v", err)
+	}
+
+	var blocks []*Block
+	if filter.BlockHash != "" {
+		block, ok := n.blocks[strings.ToLower(filter.BlockHash)]
+		if !ok {
+			return nil, errors.New("unknown block")
+		}
+		blocks = []*Block{block}
+	} else {
+		from, to := n.head(0), n.head(0)
+		var err error
+		if filter.FromBlock != nil {
+			if from, err = n.blockParam(filter.FromBlock); err != nil {
+				return nil, err
+			}
+		}
+		if filter.ToBlock != nil {
+			if to, err = n.blockParam(filter.ToBlock); err != nil {
+				return nil, err
+			}
+		}
+		if from == nil || to == nil {
+			return []*ethclient.Log{}, nil
+		}
+		if from.Number > to.Number {
+			return nil, errors.New("invalid block range params")
+		}
+		blocks = n.chain[from.Number : to.Number+1]
+	}
+
+	addresses, err := stringOrList(filter.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %v", err)
+	}
+	topics := make([][]string, len(filter.Topics))
+	for i, raw := range filter.Topics {
+		if topics[i], err = stringOrList(raw); err != nil {
+			return nil, fmt.Errorf("invalid topic %d: %v", i, err)
+		}
+	}
+
+	logs := []*ethclient.Log{}
+	for _, block := range blocks {
+		for _, receipt := range block.Receipts {
+			for _, log := range receipt.Logs {
+				if matchLog(log, addresses, topics) {
+					logs = append(logs, log)
+				}
+			}
+		}
+	}
+	return logs, nil
+}
+
+func matchLog(log *ethclient.Log, addresses []string, topics [][]string) bool {
+	if len(addresses) > 0 && !contains(addresses, log.Address) {
+		return false
+	}
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+	for i, values := range topics {
+		if len(values) > 0 && !contains(values, log.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringOrList decodes a null, a string or a list of strings.
+func stringOrList(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+	var list []string
+	err := json.Unmarshal(raw, &list)
+	return list, err
+}
+
+// accountParams decodes the address and block params of state methods.
+func (n *Node) accountParams(params []json.RawMessage) (string, *Block, error) {
+	if len(params) != 2 {
+		return "", nil, errors.New("expected address and block params")
+	}
+	var address string
+	if err := json.Unmarshal(params[0], &address); err != nil {
+		return "", nil, fmt.Errorf("invalid address: %v", err)
+	}
+	block, err := n.blockParam(params[1])
+	if err != nil {
+		return "", nil, err
+	}
+	if block == nil {
+		return "", nil, &ethclient.RPCError{Code: ethclient.CodeServerError, Message: "header not found"}
+	}
+	return strings.ToLower(address), block, nil
+}
+
+// txParam returns the canonical block including the transaction with the
+// hash given in params, nil if unknown.
+func (n *Node) txParam(params []json.RawMessage) (*Block, int, error) {
+	if len(params) != 1 {
+		return nil, 0, errors.New("expected transaction hash param")
+	}
+	var hash string
+	if err := json.Unmarshal(params[0], &hash); err != nil {
+		return nil, 0, fmt.Errorf("invalid transaction hash: %v", err)
+	}
+	block, ok := n.txBlocks[strings.ToLower(hash)]
+	if !ok {
+		return nil, 0, nil
+	}
+	for i, tx := range block.Transactions {
+		if tx.Hash == strings.ToLower(hash) {
+			return block, i, nil
+		}
+	}
+	return nil, 0, nil
+}
+
+// blockParam returns the block referenced by a number, a tag, a hash or an
+// EIP-1898 object, nil if unknown.
+func (n *Node) blockParam(raw json.RawMessage) (*Block, error) {
+	var ref string
+	if err := json.Unmarshal(raw, &ref); err != nil {
+		var object struct {
+			BlockNumber      string `json:"blockNumber"`
+			BlockHash        string `json:"blockHash"`
+			RequireCanonical bool   `json:"requireCanonical"`
+		}
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, fmt.Errorf("invalid block param: %s", raw)
+		}
+		if object.BlockHash == "" {
+			ref = object.BlockNumber
+		} else {
+			block, ok := n.blocks[strings.ToLower(object.BlockHash)]
+			if !ok {
+				return nil, &ethclient.RPCError{Code: ethclient.CodeServerError, Message: "header for hash not found"}
+			}
+			if object.RequireCanonical && !n.canonical(block) {
+				return nil, &ethclient.RPCError{Code: ethclient.CodeServerError, Message: "hash is not currently canonical"}
+			}
+			return block, nil
+		}
+	}
+
+	switch ref {
+	case ethclient.TagLatest, ethclient.TagPending:
+		return n.head(0), nil
+	case ethclient.TagSafe:
+		return n.head(n.safeDepth), nil
+	case ethclient.TagFinalized:
+		return n.head(n.finalizedDepth), nil
+	case ethclient.TagEarliest:
+		return n.chain[0], nil
+	}
+	if len(ref) == 66 {
+		return n.blocks[strings.ToLower(ref)], nil
+	}
+
+	number, ok := new(big.Int).SetString(strings.TrimPrefix(ref, "0x"), 16)
+	if !ok || !strings.HasPrefix(ref, "0x") {
+		return nil, fmt.Errorf("invalid block param: %s", ref)
+	}
+	if !number.IsInt64() || number.Int64() >= int64(len(n.chain)) {
+		return nil, nil
+	}
+	return n.chain[number.Int64()], nil
+}
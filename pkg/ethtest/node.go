@@ -0,0 +1,360 @@
+// Package ethtest provides an in-process Ethereum JSON-RPC node for tests,
+// serving a programmable in-memory chain over HTTP.
+package ethtest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+)
+
+const (
+	// DefaultChainID is the chain id of the node, mainnet.
+	DefaultChainID = 1
+
+	// DefaultSafeDepth and DefaultFinalizedDepth are the number of blocks
+	// between the head and the safe or finalized block, about one and two
+	// epochs like on mainnet.
+	DefaultSafeDepth      = 32
+	DefaultFinalizedDepth = 64
+)
+
+// MethodHandler serves a method the node does not implement, or overrides
+// one it does. params are the raw parameters of the request, an
+// *ethclient.RPCError return is sent as the error of the response.
+type MethodHandler func(params []json.RawMessage) (interface{}, error)
+
+// Fault makes the node misbehave on a method.
+type Fault struct {
+	// Latency delays the response.
+	Latency time.Duration
+	// Error is returned instead of the result.
+	Error *ethclient.RPCError
+	// HTTPStatus fails the whole HTTP request with the status, e.g. 429.
+	HTTPStatus int
+	// Times is the number of requests affected, all of them if 0.
+	Times int
+}
+
+// Node is a JSON-RPC node backed by an in-memory chain. Blocks are only
+// mined on demand, with Mine, and can be reorganized with Reorg. Balances
+// are set with SetBalance and are the same at every block. Transactions
+// sent with Send wait in the mempool until mined with MinePending.
+type Node struct {
+	server *httptest.Server
+
+	lock     sync.Mutex
+	chainID  uint64
+	chain    []*Block
+	blocks   map[string]*Block
+	txBlocks map[string]*Block
+	// forks is the number of reorgs, so that replaced blocks get new
+	// hashes.
+	forks          int
+	safeDepth      int
+	finalizedDepth int
+	balances       map[string]*big.Int
+	withdrawals    uint64
+	pool           []*pendingTx
+	// filters holds the hashes of the transactions sent since every
+	// pending transaction filter was last polled.
+	filters     map[string][]string
+	filterCount int
+	handlers    map[string]MethodHandler
+	faults      map[string]*Fault
+	calls       map[string]int
+}
+
+// NewNode starts a node with a genesis block. It must be closed once done.
+func NewNode() *Node {
+	genesis := &Block{Hash: hash("genesis"), ParentHash: zeroHash, Timestamp: genesisTime}
+	n := &Node{
+		chainID:        DefaultChainID,
+		chain:          []*Block{genesis},
+		blocks:         map[string]*Block{genesis.Hash: genesis},
+		txBlocks:       make(map[string]*Block),
+		safeDepth:      DefaultSafeDepth,
+		finalizedDepth: DefaultFinalizedDepth,
+		balances:       make(map[string]*big.Int),
+		filters:        make(map[string][]string),
+		handlers:       make(map[string]MethodHandler),
+		faults:         make(map[string]*Fault),
+		calls:          make(map[string]int),
+	}
+	n.server = httptest.NewServer(n)
+	return n
+}
+
+// URL returns the HTTP endpoint of the node.
+func (n *Node) URL() string {
+	return n.server.URL
+}
+
+// Client returns a client of the node.
+func (n *Node) Client(opts ...ethclient.Option) *ethclient.Client {
+	return ethclient.NewETHClient(n.URL(), opts...)
+}
+
+func (n *Node) Close() {
+	n.server.Close()
+}
+
+// SetChainID sets the chain id returned by eth_chainId and net_version
+// and set in the transactions mined afterwards.
+func (n *Node) SetChainID(chainID uint64) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.chainID = chainID
+}
+
+// SetFinality sets the number of blocks between the head and the safe and
+// finalized blocks.
+func (n *Node) SetFinality(safeDepth, finalizedDepth int) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.safeDepth, n.finalizedDepth = safeDepth, finalizedDepth
+}
+
+// SetBalance sets the balance in wei of the address.
+func (n *Node) SetBalance(address string, balance *big.Int) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.balances[strings.ToLower(address)] = new(big.Int).Set(balance)
+}
+
+// Mine mines a block with the given transactions on top of the head.
+func (n *Node) Mine(txs ...Tx) *Block {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.mine(txs, nil)
+}
+
+// MineWithdrawals mines a block with the given withdrawals and no
+// transactions on top of the head.
+func (n *Node) MineWithdrawals(withdrawals ...Withdrawal) *Block {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.mine(nil, withdrawals)
+}
+
+// MineEmpty mines count empty blocks and returns the last one.
+func (n *Node) MineEmpty(count int) *Block {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	block := n.chain[len(n.chain)-1]
+	for i := 0; i < count; i++ {
+		block = n.mine(nil, nil)
+	}
+	return block
+}
+
+// Reorg removes the last depth blocks from the canonical chain, the
+// blocks mined next replace them with new hashes. Removed blocks are still
+// served by hash, their transactions and receipts no longer are until
+// they are mined again, keeping their hash.
+func (n *Node) Reorg(depth int) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if depth >= len(n.chain) {
+		depth = len(n.chain) - 1
+	}
+	for _, block := range n.chain[len(n.chain)-depth:] {
+		for _, tx := range block.Transactions {
+			delete(n.txBlocks, tx.Hash)
+		}
+	}
+	n.chain = n.chain[:len(n.chain)-depth]
+	n.forks++
+}
+
+// Head returns the head block.
+func (n *Node) Head() *Block {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.head(0)
+}
+
+// BlockByNumber returns the canonical block with the given number, nil if
+// there is none.
+func (n *Node) BlockByNumber(number int) *Block {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if number < 0 || number >= len(n.chain) {
+		return nil
+	}
+	return n.chain[number]
+}
+
+// HandleMethod serves method with handler, e.g. eth_call results or traces.
+func (n *Node) HandleMethod(method string, handler MethodHandler) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.handlers[method] = handler
+}
+
+// SetFault makes the requests of method misbehave, replacing any previous
+// fault of the method.
+func (n *Node) SetFault(method string, fault Fault) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.faults[method] = &fault
+}
+
+// ClearFaults makes every method behave again.
+func (n *Node) ClearFaults() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.faults = make(map[string]*Fault)
+}
+
+// Calls returns the number of requests received for method, batch elements
+// counting for one each.
+func (n *Node) Calls(method string) int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.calls[method]
+}
+
+// rpcRequest is a request received by the node.
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// rpcResponse is a response sent by the node, Result is sent as null when
+// there is no Error.
+type rpcResponse struct {
+	Jsonrpc string
+	ID      json.RawMessage
+	Result  interface{}
+	Error   *ethclient.RPCError
+}
+
+func (r rpcResponse) MarshalJSON() ([]byte, error) {
+	msg := map[string]interface{}{"jsonrpc": r.Jsonrpc, "id": r.ID}
+	if r.Error != nil {
+		msg["error"] = r.Error
+	} else {
+		msg["result"] = r.Result
+	}
+	return json.Marshal(msg)
+}
+
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var reqs []rpcRequest
+	batch := len(body) > 0 && body[0] == '['
+	if batch {
+		err = json.Unmarshal(body, &reqs)
+	} else {
+		reqs = make([]rpcRequest, 1)
+		err = json.Unmarshal(body, &reqs[0])
+	}
+	if err != nil {
+		writeJSON(w, rpcResponse{
+			Jsonrpc: ethclient.ApiVersion,
+			ID:      json.RawMessage("null"),
+			Error:   &ethclient.RPCError{Code: ethclient.CodeParseError, Message: err.Error()},
+		})
+		return
+	}
+
+	resps := make([]rpcResponse, len(reqs))
+	for i, req := range reqs {
+		resp, status := n.handle(r.Context(), req)
+		if status != 0 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		resps[i] = resp
+	}
+	if batch {
+		writeJSON(w, resps)
+	} else {
+		writeJSON(w, resps[0])
+	}
+}
+
+// handle answers a request, or returns the HTTP status failing it.
+func (n *Node) handle(ctx context.Context, req rpcRequest) (rpcResponse, int) {
+	resp := rpcResponse{Jsonrpc: ethclient.ApiVersion, ID: req.ID}
+
+	fault := n.fault(req.Method)
+	if fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-ctx.Done():
+		}
+	}
+	if fault.HTTPStatus != 0 {
+		return resp, fault.HTTPStatus
+	}
+	if fault.Error != nil {
+		resp.Error = fault.Error
+		return resp, 0
+	}
+
+	n.lock.Lock()
+	handler, ok := n.handlers[req.Method]
+	n.lock.Unlock()
+	if !ok {
+		handler, ok = n.builtin(req.Method)
+	}
+	if !ok {
+		resp.Error = &ethclient.RPCError{
+			Code:    ethclient.CodeMethodNotFound,
+			Message: "the method " + req.Method + " does not exist/is not available",
+		}
+		return resp, 0
+	}
+
+	result, err := handler(req.Params)
+	if err != nil {
+		rpcErr, ok := err.(*ethclient.RPCError)
+		if !ok {
+			rpcErr = &ethclient.RPCError{Code: ethclient.CodeInvalidParams, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+		return resp, 0
+	}
+	resp.Result = result
+	return resp, 0
+}
+
+// fault counts the request and returns the fault of its method, if any.
+func (n *Node) fault(method string) Fault {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.calls[method]++
+
+	fault, ok := n.faults[method]
+	if !ok {
+		return Fault{}
+	}
+	if fault.Times > 0 {
+		fault.Times--
+		if fault.Times == 0 {
+			delete(n.faults, method)
+		}
+	}
+	return *fault
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,199 @@
+package ethtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+)
+
+const (
+	alice = "0x00000000000000000000000000000000000a11ce"
+	bob   = "0x0000000000000000000000000000000000000b0b"
+	token = "0x000000000000000000000000000000000000701c"
+
+	transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+)
+
+func TestNodeChain(t *testing.T) {
+	node := NewNode()
+	defer node.Close()
+	node.SetFinality(1, 2)
+	node.SetBalance(alice, big.NewInt(1000))
+	client := node.Client()
+	ctx := context.Background()
+
+	node.MineEmpty(2)
+	mined := node.Mine(
+		Tx{From: alice, To: bob, Value: big.NewInt(10)},
+		Tx{From: alice, To: token, Gas: 50000, Logs: []Log{{Address: token, Topics: []string{transferTopic}}}},
+		Tx{From: bob, To: alice, Failed: true},
+	)
+	if mined.Number != 3 {
+		t.Fatalf("expected block 3, got %d", mined.Number)
+	}
+
+	block, err := client.BlockByNumber(ctx, 3)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if block.Hash != mined.Hash || len(block.Transactions) != 3 || block.Transactions[1].Nonce != "0x1" {
+		t.Errorf("unexpected block %+v", block)
+	}
+	if header, err := client.HeaderByHash(ctx, mined.Hash); err != nil || header.ParentHash != node.BlockByNumber(2).Hash {
+		t.Errorf("unexpected header %+v: %v", header, err)
+	}
+	if count, err := client.BlockTransactionCountByNumber(ctx, 3); err != nil || count != 3 {
+		t.Errorf("expected 3 transactions, got %d: %v", count, err)
+	}
+	if _, err := client.UncleByBlockHashAndIndex(ctx, mined.Hash, 0); !errors.Is(err, ethclient.ErrBlockNotFound) {
+		t.Errorf("expected no uncle, got %v", err)
+	}
+	if count, err := client.UncleCount(ctx, ethclient.BlockHashRef(mined.Hash, false)); err != nil || count != 0 {
+		t.Errorf("expected no uncle, got %d: %v", count, err)
+	}
+	if tx, err := client.TransactionInBlock(ctx, ethclient.BlockNumberRef(3), 2); err != nil || tx.Hash != mined.Transactions[2].Hash {
+		t.Errorf("unexpected transaction %+v: %v", tx, err)
+	}
+	if _, err := client.TransactionInBlock(ctx, ethclient.BlockHashRef(mined.Hash, false), 3); !errors.Is(err, ethclient.ErrTransactionNotFound) {
+		t.Errorf("expected ErrTransactionNotFound, got %v", err)
+	}
+	if progress, err := client.SyncProgress(ctx); err != nil || progress != nil {
+		t.Errorf("expected node in sync, got %+v: %v", progress, err)
+	}
+	if _, err := client.BlockByNumber(ctx, 4); !errors.Is(err, ethclient.ErrBlockNotFound) {
+		t.Errorf("expected block not found, got %v", err)
+	}
+	if finalized, err := client.BlockNumberAt(ctx, ethclient.FinalizedBlock); err != nil || finalized != 1 {
+		t.Errorf("expected finalized block 1, got %d: %v", finalized, err)
+	}
+
+	receipts, err := client.TransactionReceipts(ctx, []string{mined.Transactions[1].Hash, mined.Transactions[2].Hash})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if receipts[0].CumulativeGasUsed != "0x11558" || len(receipts[0].Logs) != 1 || receipts[1].Status != "0x0" {
+		t.Errorf("unexpected receipts %+v, %+v", receipts[0], receipts[1])
+	}
+	logs, err := client.FilterLogs(ctx, *ethclient.NewFilter().Range(0, 3).Address(token).Topic(0, transferTopic))
+	if err != nil || len(logs) != 1 || logs[0].TransactionHash != mined.Transactions[1].Hash {
+		t.Errorf("unexpected logs %v: %v", logs, err)
+	}
+
+	if balance, err := client.BalanceAt(ctx, alice, ethclient.LatestBlock); err != nil || balance.Int64() != 1000 {
+		t.Errorf("unexpected balance %v: %v", balance, err)
+	}
+	if nonce, err := client.NonceAt(ctx, alice, ethclient.LatestBlock); err != nil || nonce != 2 {
+		t.Errorf("unexpected nonce %d: %v", nonce, err)
+	}
+
+	// The transfer moves to another block, the failed transaction is gone.
+	node.Reorg(1)
+	node.MineEmpty(1)
+	replaced := node.Mine(Tx{From: alice, To: bob, Value: big.NewInt(10)})
+	if head, err := client.BlockNumber(ctx); err != nil || head != 4 {
+		t.Errorf("expected head 4, got %d: %v", head, err)
+	}
+	if _, err := client.TransactionReceipt(ctx, mined.Transactions[2].Hash); !errors.Is(err, ethclient.ErrReceiptNotFound) {
+		t.Errorf("expected reorganized receipt to be gone, got %v", err)
+	}
+	receipt, err := client.TransactionReceipt(ctx, mined.Transactions[0].Hash)
+	if err != nil || receipt.BlockHash != replaced.Hash {
+		t.Errorf("expected transfer in block %s, got %+v: %v", replaced.Hash, receipt, err)
+	}
+	if block, err := client.BlockByHash(ctx, mined.Hash); err != nil || len(block.Transactions) != 3 {
+		t.Errorf("expected reorganized block to be served by hash, got %+v: %v", block, err)
+	}
+	if _, err := client.BalanceAt(ctx, alice, ethclient.BlockHashRef(mined.Hash, true)); err == nil {
+		t.Error("expected reorganized block not to be canonical")
+	}
+}
+
+func TestNodeFaults(t *testing.T) {
+	node := NewNode()
+	defer node.Close()
+	client := node.Client()
+	ctx := context.Background()
+
+	node.SetFault(ethclient.GetBlockbusterMethod, Fault{Error: &ethclient.RPCError{Code: ethclient.CodeServerError, Message: "injected"}, Times: 1})
+	if _, err := client.BlockNumber(ctx); err == nil {
+		t.Error("expected injected error")
+	}
+	if _, err := client.BlockNumber(ctx); err != nil {
+		t.Errorf("expected the fault to be over, got %v", err)
+	}
+
+	node.SetFault(ethclient.GetBlockbusterMethod, Fault{HTTPStatus: http.StatusTooManyRequests})
+	if _, err := client.BlockNumber(ctx); !errors.Is(err, ethclient.ErrRateLimited) {
+		t.Errorf("expected rate limited, got %v", err)
+	}
+
+	node.SetFault(ethclient.GetBlockbusterMethod, Fault{Latency: time.Second})
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.BlockNumber(timeout); err == nil {
+		t.Error("expected timeout")
+	}
+	node.ClearFaults()
+	if calls := node.Calls(ethclient.GetBlockbusterMethod); calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+
+	node.HandleMethod(ethclient.EthCall, func(params []json.RawMessage) (interface{}, error) {
+		return "0x2a", nil
+	})
+	result, err := client.Call(ctx, ethclient.CallMsg{To: token}, ethclient.LatestBlock, nil)
+	if err != nil || len(result) != 1 || result[0] != 0x2a {
+		t.Errorf("unexpected call result %x: %v", result, err)
+	}
+	if _, err := client.TraceBlock(ctx, 0); err == nil {
+		t.Error("expected unknown method to fail")
+	}
+}
+
+func TestNodeMempool(t *testing.T) {
+	node := NewNode()
+	defer node.Close()
+	client := node.Client()
+	ctx := context.Background()
+
+	filter, err := client.NewPendingTransactionFilter(ctx)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	sent := node.Send(Tx{From: alice, To: bob}, Tx{From: alice, To: token})
+	if hashes, err := client.PendingTransactions(ctx, filter); err != nil || len(hashes) != 2 || hashes[1] != sent[1].Hash {
+		t.Errorf("unexpected pending transactions %v: %v", hashes, err)
+	}
+	if tx, err := client.TransactionByHash(ctx, sent[1].Hash); err != nil || !tx.Pending() || tx.Nonce != "0x1" {
+		t.Errorf("unexpected pending transaction %+v: %v", tx, err)
+	}
+
+	// The second transaction is evicted once its nonce is mined.
+	replacement := node.Replace(sent[0].Hash, Tx{From: alice, To: alice})
+	node.Mine(Tx{From: alice, To: bob, Value: big.NewInt(1)}, Tx{From: alice, To: bob, Value: big.NewInt(2)})
+	if hashes, err := client.PendingTransactions(ctx, filter); err != nil || len(hashes) != 1 || hashes[0] != replacement.Hash {
+		t.Errorf("unexpected pending transactions %v: %v", hashes, err)
+	}
+	for _, hash := range []string{sent[0].Hash, sent[1].Hash, replacement.Hash} {
+		if _, err := client.TransactionByHash(ctx, hash); !errors.Is(err, ethclient.ErrTransactionNotFound) {
+			t.Errorf("expected %s to be gone, got %v", hash, err)
+		}
+	}
+
+	node.Send(Tx{From: bob, To: alice})
+	if block := node.MinePending(); len(block.Transactions) != 1 || block.Transactions[0].From != bob {
+		t.Errorf("unexpected mined block %+v", block)
+	}
+	if removed, err := client.UninstallFilter(ctx, filter); err != nil || !removed {
+		t.Errorf("expected the filter to be removed, got %v: %v", removed, err)
+	}
+	if _, err := client.PendingTransactions(ctx, filter); !errors.Is(err, ethclient.ErrFilterNotFound) {
+		t.Errorf("expected filter not found, got %v", err)
+	}
+}
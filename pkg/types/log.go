@@ -0,0 +1,9 @@
+package types
+
+// Log is an event emitted during the execution of a transaction.
+type Log struct {
+	Address  string   `json:"address"`
+	Topics   []string `json:"topics"`
+	Data     string   `json:"data"`
+	LogIndex string   `json:"logIndex"`
+}
@@ -11,4 +11,55 @@ type Transaction struct {
 	Gas         string `json:"gas"`
 	GasPrice    string `json:"gasPrice"`
 	Input       string `json:"input"`
+
+	// Execution outcome, taken from the transaction receipt, or one of the
+	// pending statuses for transactions seen in the mempool.
+	Status            string `json:"status,omitempty"`
+	GasUsed           string `json:"gasUsed,omitempty"`
+	CumulativeGasUsed string `json:"cumulativeGasUsed,omitempty"`
+	EffectiveGasPrice string `json:"effectiveGasPrice,omitempty"`
+	ContractAddress   string `json:"contractAddress,omitempty"`
+	Logs              []*Log `json:"logs,omitempty"`
+
+	// Kind tells records which are not top level transactions apart, it is
+	// empty for transactions.
+	Kind string `json:"kind,omitempty"`
+	// CallType and CallPath locate an internal call in the call tree of
+	// its transaction, see ethclient.InternalCall.
+	CallType string `json:"callType,omitempty"`
+	CallPath []int  `json:"callPath,omitempty"`
+
+	// WithdrawalIndex and ValidatorIndex identify a beacon chain
+	// withdrawal, see ethclient.ETHWithdrawal.
+	WithdrawalIndex string `json:"withdrawalIndex,omitempty"`
+	ValidatorIndex  string `json:"validatorIndex,omitempty"`
+
+	// ReplacedBy is the hash of the transaction sent with the same nonce
+	// which replaced a pending one, if known.
+	ReplacedBy string `json:"replacedBy,omitempty"`
 }
+
+// Record kinds.
+const (
+	// KindInternal is an ether transfer made by a contract during the
+	// execution of the transaction with the same hash.
+	KindInternal = "internal"
+	// KindWithdrawal is a withdrawal of staked ether from the beacon chain
+	// to the To address, in Value wei. It has no hash nor sender.
+	KindWithdrawal = "withdrawal"
+)
+
+// Receipt statuses.
+const (
+	StatusFailed  = "0x0"
+	StatusSuccess = "0x1"
+)
+
+// Pending statuses. A pending transaction gets a receipt status once
+// mined, or is dropped from the mempool or replaced by another
+// transaction with the same nonce.
+const (
+	StatusPending  = "pending"
+	StatusDropped  = "dropped"
+	StatusReplaced = "replaced"
+)
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Decimals of the ether denominations, relative to wei.
+const (
+	GweiDecimals  = 9
+	EtherDecimals = 18
+)
+
+// FormatEther formats an amount of wei in ether, e.g. "1.5".
+func FormatEther(wei *big.Int) string {
+	return FormatUnits(wei, EtherDecimals)
+}
+
+// FormatGwei formats an amount of wei in gwei, e.g. "30.000000001".
+func FormatGwei(wei *big.Int) string {
+	return FormatUnits(wei, GweiDecimals)
+}
+
+// FormatUnits formats the integer amount with the given number of
+// decimals, without trailing zeros.
+func FormatUnits(amount *big.Int, decimals int) string {
+	if amount == nil {
+		return "0"
+	}
+
+	digits := new(big.Int).Abs(amount).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	integer, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	if fraction == "" {
+		return sign + integer
+	}
+	return sign + integer + "." + fraction
+}
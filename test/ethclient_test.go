@@ -2,6 +2,9 @@ package test
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"path/filepath"
 	"testing"
 
 	"github.com/352174109/trustwallet-homework/pkg/ethclient"
@@ -9,11 +12,91 @@ import (
 
 const (
 	endPoint = "https://cloudflare-eth.com"
+
+	// mainnetBlock is the block replayed by the mainnet cassettes.
+	mainnetBlock = 20763286
+)
+
+// record re-records the cassettes against the live endpoint:
+//
+//	go test ./test -record
+var (
+	record   = flag.Bool("record", false, "record the cassettes against -endpoint")
+	endpoint = flag.String("endpoint", endPoint, "endpoint the cassettes are recorded against")
 )
 
-func Test_BlockNumber(t *testing.T) {
-	cli := ethclient.NewETHClient(endPoint)
+// newClient returns a client replaying the named cassette of testdata in
+// the given mode, or recording it with -record.
+func newClient(t *testing.T, name string, mode ethclient.ReplayMode) *ethclient.Client {
+	path := filepath.Join("testdata", name+".json")
+	if *record {
+		cassette := ethclient.NewCassette()
+		t.Cleanup(func() {
+			if err := cassette.Save(path); err != nil {
+				t.Error(err.Error())
+			}
+		})
+		return ethclient.NewETHClient(*endpoint, ethclient.WithRecorder(cassette))
+	}
+
+	cassette, err := ethclient.LoadCassette(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	cli, err := ethclient.NewReplayClient(cassette, mode)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return cli
+}
+
+func Test_Mainnet(t *testing.T) {
+	cli := newClient(t, "mainnet", ethclient.ReplayStrict)
 	number, err := cli.BlockNumber(context.Background())
-	t.Log(err)
-	t.Log(number)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if number < mainnetBlock {
+		t.Errorf("unexpected block number %d", number)
+	}
+
+	block, err := cli.BlockByNumber(context.Background(), mainnetBlock)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if block.Hash != "0x271d21523a4ed7b0975b6f95a47615e86ba74b8f8a1035c04c3cd2dd70810c40" {
+		t.Errorf("unexpected block hash %s", block.Hash)
+	}
+	if len(block.Transactions) == 0 {
+		t.Error("expected transactions in the block")
+	}
+	if len(block.Withdrawals) == 0 {
+		t.Fatal("expected withdrawals in the block")
+	}
+	withdrawal := block.Withdrawals[0]
+	if withdrawal.Index != 59653195 || withdrawal.ValidatorIndex != 1453489 ||
+		withdrawal.Address != "0xb23c002bc65c6bb539aad4c11d606ef4f5502c93" || withdrawal.Amount != 19092255 {
+		t.Errorf("unexpected withdrawal %+v", withdrawal)
+	}
+}
+
+func Test_MainnetLenient(t *testing.T) {
+	if *record {
+		t.Skip("replays the cassette of Test_Mainnet")
+	}
+	cli := newClient(t, "mainnet", ethclient.ReplayLenient)
+	ctx := context.Background()
+
+	// Out of the recorded order, the head number being repeated.
+	if block, err := cli.BlockByNumber(ctx, mainnetBlock); err != nil || block.Number != "0x13cd296" {
+		t.Fatalf("unexpected block %+v: %v", block, err)
+	}
+	for i := 0; i < 2; i++ {
+		if number, err := cli.BlockNumber(ctx); err != nil || number != mainnetBlock {
+			t.Errorf("unexpected block number %d: %v", number, err)
+		}
+	}
+	if _, err := cli.BlockByNumber(ctx, mainnetBlock+1); !errors.Is(err, ethclient.ErrCassetteMismatch) {
+		t.Errorf("expected ErrCassetteMismatch, got %v", err)
+	}
 }
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/352174109/trustwallet-homework/internal/dal"
+	"github.com/352174109/trustwallet-homework/internal/service"
+	"github.com/352174109/trustwallet-homework/pkg/ethclient"
+	"github.com/352174109/trustwallet-homework/pkg/types"
+)
+
+// withdrawalAddress receives the withdrawal of validator 1453489 in
+// mainnetBlock.
+const withdrawalAddress = "0xb23c002bc65c6bb539aad4c11d606ef4f5502c93"
+
+func Test_MainnetScan(t *testing.T) {
+	cli := newClient(t, "scan", ethclient.ReplayLenient)
+	ctx := context.Background()
+	subscribeDal, _ := dal.NewSubscribeDal()
+	transactionDal, _ := dal.NewTransactionDal()
+	parser, err := service.NewEthereumParser(subscribeDal, transactionDal, cli)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	parser.Subscribe(ctx, withdrawalAddress)
+
+	scanner := service.NewScan(ctx, transactionDal, subscribeDal, cli, mainnetBlock-1, 10*time.Millisecond,
+		service.WithHeadBlock(ethclient.BlockNumberRef(mainnetBlock)), service.WithChainID(1))
+	defer scanner.Stop()
+	scanner.Run()
+	deadline := time.Now().Add(5 * time.Second)
+	for parser.GetCurrentBlock(ctx) < mainnetBlock {
+		if time.Now().After(deadline) {
+			t.Fatalf("scanner stuck at block %d", parser.GetCurrentBlock(ctx))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	transactions := parser.GetTransactions(ctx, withdrawalAddress)
+	if len(transactions) != 1 {
+		t.Fatalf("expected 1 withdrawal, got %+v", transactions)
+	}
+	withdrawal := transactions[0]
+	if withdrawal.Kind != types.KindWithdrawal || withdrawal.To != withdrawalAddress || withdrawal.BlockNumber != "0x13cd296" ||
+		withdrawal.WithdrawalIndex != "0x38e3c4b" || withdrawal.ValidatorIndex != "0x162db1" || withdrawal.Value != "0x43d44e053c7600" {
+		t.Errorf("unexpected withdrawal %+v", withdrawal)
+	}
+}